@@ -0,0 +1,103 @@
+package route53
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/route53/types"
+	"github.com/libdns/libdns"
+)
+
+// Alias is a Route53 alias record: a record set that answers with the
+// records of another AWS resource, such as a CloudFront distribution, a load
+// balancer or another record set in the same hosted zone. With
+// Provider.AliasRecords, GetRecords returns alias record sets as Alias
+// values, and the record methods accept them.
+//
+// Its RR has the aliased record type and the alias target as data. Since
+// that data is a host name rather than, for example, an IP address, calling
+// Parse on the RR of an A or AAAA alias fails; use the Alias itself.
+type Alias struct {
+	// Name is the name of the record set, relative to the zone.
+	Name string
+
+	// Type is the record type the alias answers for, such as "A" or "AAAA".
+	Type string
+
+	// Target is the DNS name of the alias target, for example
+	// "d111111abcdef8.cloudfront.net.".
+	Target string
+
+	// HostedZoneID is the hosted zone ID of the alias target. For targets
+	// in the same hosted zone, it is that zone's ID.
+	HostedZoneID string
+
+	// EvaluateTargetHealth makes Route53 consider the health of the target
+	// when answering queries.
+	EvaluateTargetHealth bool
+}
+
+// RR returns the alias as a libdns.RR whose data is the alias target. Alias
+// record sets have no TTL of their own, so TTL is zero.
+func (a Alias) RR() libdns.RR {
+	return libdns.RR{
+		Name: a.Name,
+		Type: a.Type,
+		Data: a.Target,
+	}
+}
+
+// target converts the alias into a Route53 AliasTarget.
+func (a Alias) target() *types.AliasTarget {
+	return &types.AliasTarget{
+		DNSName:              aws.String(a.Target),
+		HostedZoneId:         aws.String(a.HostedZoneID),
+		EvaluateTargetHealth: a.EvaluateTargetHealth,
+	}
+}
+
+// aliasFromTarget converts a Route53 AliasTarget into an Alias.
+func aliasFromTarget(name, recordType string, target *types.AliasTarget) Alias {
+	return Alias{
+		Name:                 name,
+		Type:                 recordType,
+		Target:               aws.ToString(target.DNSName),
+		HostedZoneID:         aws.ToString(target.HostedZoneId),
+		EvaluateTargetHealth: target.EvaluateTargetHealth,
+	}
+}
+
+// asAlias reports whether record is an Alias, by value or by pointer.
+func asAlias(record libdns.Record) (Alias, bool) {
+	switch a := record.(type) {
+	case Alias:
+		return a, true
+	case *Alias:
+		if a != nil {
+			return *a, true
+		}
+	}
+	return Alias{}, false
+}
+
+// hidesAlias reports whether record is an Alias the record methods leave out
+// because AliasRecords is not set.
+func (p *Provider) hidesAlias(record libdns.Record) bool {
+	_, ok := asAlias(record)
+	return ok && !p.AliasRecords
+}
+
+// checkAliasRecords rejects the Alias records passed to a record method
+// unless AliasRecords is set.
+func (p *Provider) checkAliasRecords(records []libdns.Record) error {
+	for _, record := range records {
+		if p.hidesAlias(record) {
+			rr := record.RR()
+			return fmt.Errorf("alias record %s %s requires AliasRecords to be enabled", rr.Name, rr.Type)
+		}
+	}
+	return nil
+}
+
+// Interface guard.
+var _ libdns.Record = Alias{}
@@ -0,0 +1,116 @@
+package route53
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/smithy-go"
+	"github.com/aws/smithy-go/middleware"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+)
+
+// redactedHeaders are the request headers holding credentials, which are
+// never logged.
+var redactedHeaders = []string{ //nolint:gochecknoglobals // read-only list
+	"Authorization",
+	"X-Amz-Security-Token",
+	"Cookie",
+}
+
+// redacted replaces the values of redactedHeaders in the logs.
+const redacted = "REDACTED"
+
+// apiCallLog tracks the attempts of a single Route53 API call.
+type apiCallLog struct {
+	attempts int
+	lastEnd  time.Time
+}
+
+// addLoggingMiddleware adds the middleware logging every attempt of a
+// Route53 API call, including those made by the retryer, to the stack of an
+// operation.
+func (p *Provider) addLoggingMiddleware(stack *middleware.Stack) error {
+	err := stack.Initialize.Add(middleware.InitializeMiddlewareFunc("route53.APICallLog",
+		func(ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler) (
+			middleware.InitializeOutput, middleware.Metadata, error,
+		) {
+			return next.HandleInitialize(context.WithValue(ctx, contextKeyAPICallLog, &apiCallLog{}), in)
+		}), middleware.After)
+	if err != nil {
+		return err
+	}
+
+	// at the end of the finalize step, after the retryer and the signer, so
+	// it runs once per attempt with the signed request
+	return stack.Finalize.Add(middleware.FinalizeMiddlewareFunc("route53.APIAttemptLog", p.logAttempt), middleware.After)
+}
+
+// logAttempt logs an attempt of a Route53 API call at Debug level: its
+// number, the delay since the previous attempt, the outcome, the request ID
+// and the request headers with credentials redacted.
+func (p *Provider) logAttempt(
+	ctx context.Context,
+	in middleware.FinalizeInput,
+	next middleware.FinalizeHandler,
+) (middleware.FinalizeOutput, middleware.Metadata, error) {
+	if !p.Logger.Enabled(ctx, slog.LevelDebug) {
+		return next.HandleFinalize(ctx, in)
+	}
+
+	call, _ := ctx.Value(contextKeyAPICallLog).(*apiCallLog)
+	if call == nil {
+		call = &apiCallLog{}
+	}
+	call.attempts++
+	attrs := []slog.Attr{
+		slog.String("operation", awsmiddleware.GetOperationName(ctx)),
+		slog.Int("attempt", call.attempts),
+	}
+	if !call.lastEnd.IsZero() {
+		attrs = append(attrs, slog.Duration("retry_delay", time.Since(call.lastEnd)))
+	}
+	if req, ok := in.Request.(*smithyhttp.Request); ok {
+		attrs = append(attrs,
+			slog.String("method", req.Method),
+			slog.String("path", req.URL.Path),
+			slog.Any("headers", redactHeaders(req.Header)))
+	}
+
+	start := time.Now()
+	out, metadata, err := next.HandleFinalize(ctx, in)
+	call.lastEnd = time.Now()
+
+	attrs = append(attrs, slog.Duration("duration", call.lastEnd.Sub(start)))
+	if requestID, ok := awsmiddleware.GetRequestIDMetadata(metadata); ok {
+		attrs = append(attrs, slog.String("request_id", requestID))
+	}
+	if res, ok := awsmiddleware.GetRawResponse(metadata).(*smithyhttp.Response); ok && res != nil {
+		attrs = append(attrs, slog.Int("status", res.StatusCode))
+	}
+	if err != nil {
+		var apiErr smithy.APIError
+		if errors.As(err, &apiErr) {
+			attrs = append(attrs, slog.String("error_code", apiErr.ErrorCode()))
+		}
+		attrs = append(attrs, slog.String("error", err.Error()))
+	}
+
+	p.Logger.LogAttrs(ctx, slog.LevelDebug, "Route53 API attempt", attrs...)
+	return out, metadata, err
+}
+
+// redactHeaders returns a copy of the request headers for logging, with the
+// values of the headers holding credentials replaced.
+func redactHeaders(header http.Header) http.Header {
+	logged := header.Clone()
+	for _, name := range redactedHeaders {
+		if _, ok := logged[name]; ok {
+			logged[name] = []string{redacted}
+		}
+	}
+	return logged
+}
@@ -0,0 +1,76 @@
+package route53 //nolint:testpackage // Testing internal functions
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"testing"
+)
+
+func TestLogAttempts(t *testing.T) {
+	var buf bytes.Buffer
+	provider := &Provider{Logger: slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))}
+	newCannedProvider(t, provider,
+		cannedResponse{status: http.StatusBadRequest, body: throttlingResponse},
+		cannedResponse{status: http.StatusOK, body: getChangeResponse},
+	)
+
+	if _, err := provider.ChangeStatus(context.Background(), "C1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var attempts []map[string]any
+	for line := range bytes.Lines(buf.Bytes()) {
+		var event map[string]any
+		if err := json.Unmarshal(line, &event); err != nil {
+			t.Fatal(err)
+		}
+		if event["msg"] == "Route53 API attempt" {
+			attempts = append(attempts, event)
+		}
+	}
+	if len(attempts) != 2 {
+		t.Fatalf("expected 2 attempts logged, got %d: %s", len(attempts), buf.String())
+	}
+
+	first, second := attempts[0], attempts[1]
+	if first["operation"] != "GetChange" || first["attempt"] != 1.0 || first["error_code"] != "Throttling" {
+		t.Errorf("unexpected first attempt %v", first)
+	}
+	if first["request_id"] != "req-1" || first["status"] != 400.0 {
+		t.Errorf("expected the request ID and status of the first attempt, got %v", first)
+	}
+	if _, ok := first["retry_delay"]; ok {
+		t.Error("expected no retry delay on the first attempt")
+	}
+	if second["attempt"] != 2.0 || second["error_code"] != nil || second["status"] != 200.0 {
+		t.Errorf("unexpected second attempt %v", second)
+	}
+	if _, ok := second["retry_delay"]; !ok {
+		t.Error("expected the retry delay on the second attempt")
+	}
+}
+
+func TestRedactHeaders(t *testing.T) {
+	header := http.Header{
+		"Authorization":        {"AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20240102/us-east-1/route53/aws4_request"},
+		"X-Amz-Security-Token": {"secret-token"},
+		"X-Amz-Date":           {"20240102T030405Z"},
+	}
+
+	logged := redactHeaders(header)
+	if logged.Get("Authorization") != redacted || logged.Get("X-Amz-Security-Token") != redacted {
+		t.Errorf("expected the credentials redacted, got %v", logged)
+	}
+	if logged.Get("X-Amz-Date") != "20240102T030405Z" {
+		t.Errorf("expected other headers kept, got %v", logged)
+	}
+	if _, ok := logged["Cookie"]; ok {
+		t.Error("expected missing headers left out")
+	}
+	if header.Get("Authorization") == redacted {
+		t.Error("expected the request headers left untouched")
+	}
+}
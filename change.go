@@ -0,0 +1,284 @@
+package route53
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	r53 "github.com/aws/aws-sdk-go-v2/service/route53"
+	"github.com/aws/aws-sdk-go-v2/service/route53/types"
+)
+
+const (
+	// ChangeStatusPending means Route53 has accepted the change but has not
+	// yet propagated it to all of its authoritative name servers.
+	ChangeStatusPending = string(types.ChangeStatusPending)
+	// ChangeStatusInSync means the change has been applied on all Route53
+	// authoritative name servers.
+	ChangeStatusInSync = string(types.ChangeStatusInsync)
+)
+
+const (
+	// defaultRoute53SyncMinDelay is the default minimum delay between two
+	// GetChange polls, matching the AWS SDK waiter default.
+	defaultRoute53SyncMinDelay = 30 * time.Second
+	// defaultRoute53SyncMaxDelay is the default maximum delay between two
+	// GetChange polls, matching the AWS SDK waiter default.
+	defaultRoute53SyncMaxDelay = 120 * time.Second
+)
+
+// Change is a handle to a change batch submitted to Route53. It can be passed
+// to ChangeStatus or WaitForChange to follow the change after the record
+// method that submitted it has returned.
+type Change struct {
+	// ID is the Route53 change ID, for example "/change/C2682N5HXP0BZ4".
+	ID string `json:"id"`
+
+	// SubmittedAt is the time Route53 reports the change was submitted.
+	SubmittedAt time.Time `json:"submitted_at"`
+
+	// Status is the last observed status, ChangeStatusPending or
+	// ChangeStatusInSync.
+	Status string `json:"status"`
+
+	// Zone is the zone the change was made to. Empty for handles not
+	// obtained from a ChangeRecorder.
+	Zone string `json:"zone,omitempty"`
+
+	// HostedZoneID is the ID of the hosted zone the change was made to.
+	HostedZoneID string `json:"hosted_zone_id,omitempty"`
+
+	// RecordSets are the record sets the change modified, with their values
+	// before and after it. Revert uses them to undo the change.
+	RecordSets []RecordSetChange `json:"record_sets,omitempty"`
+}
+
+// ChangeRecorder collects the changes submitted by record methods called with
+// a context returned by WithChangeRecorder. It is safe for concurrent use, so
+// one recorder can be shared by many concurrent calls.
+type ChangeRecorder struct {
+	mu      sync.Mutex
+	changes []Change
+}
+
+// Changes returns the changes recorded so far, in submission order.
+func (r *ChangeRecorder) Changes() []Change {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Change(nil), r.changes...)
+}
+
+// record appends a change to the recorder.
+func (r *ChangeRecorder) record(c Change) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.changes = append(r.changes, c)
+}
+
+// setStatus sets the status of the recorded change with the given ID.
+func (r *ChangeRecorder) setStatus(id, status string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.changes {
+		if r.changes[i].ID == id {
+			r.changes[i].Status = status
+		}
+	}
+}
+
+// WithChangeRecorder returns a context that makes the record methods
+// (AppendRecords, SetRecords, DeleteRecords) report every change they submit
+// to r.
+//
+// Combined with WaitForRoute53Sync disabled, this lets callers submit many
+// changes concurrently — for example one ACME challenge per SAN — and then
+// wait for all of them at once with WaitForChanges.
+func WithChangeRecorder(ctx context.Context, r *ChangeRecorder) context.Context {
+	return context.WithValue(ctx, contextKeyChangeRecorder, r)
+}
+
+// recordChange reports c to the ChangeRecorder carried by ctx, if any.
+func recordChange(ctx context.Context, c Change) {
+	if r, ok := ctx.Value(contextKeyChangeRecorder).(*ChangeRecorder); ok && r != nil {
+		r.record(c)
+	}
+}
+
+// changeFromInfo converts the Route53 ChangeInfo into a Change.
+func changeFromInfo(info *types.ChangeInfo) Change {
+	if info == nil {
+		return Change{}
+	}
+	return Change{
+		ID:          aws.ToString(info.Id),
+		SubmittedAt: aws.ToTime(info.SubmittedAt),
+		Status:      string(info.Status),
+	}
+}
+
+// ChangeStatus returns the current state of a previously submitted change.
+// The id may be given with or without the "/change/" prefix.
+func (p *Provider) ChangeStatus(ctx context.Context, id string) (_ Change, err error) {
+	ctx, end := p.startSpan(ctx, "ChangeStatus", changeAttributes(id))
+	defer end(&err)
+
+	out, err := p.client.GetChange(ctx, &r53.GetChangeInput{Id: aws.String(changeID(id))})
+	if err != nil {
+		var nsc *types.NoSuchChange
+		if errors.As(err, &nsc) {
+			return Change{}, fmt.Errorf("NoSuchChange: %w", err)
+		}
+		return Change{}, err
+	}
+
+	return changeFromInfo(out.ChangeInfo), nil
+}
+
+// WaitForChange blocks until the change is INSYNC, Route53MaxWait elapses or
+// ctx is done. The id may be given with or without the "/change/" prefix.
+func (p *Provider) WaitForChange(ctx context.Context, id string) (err error) {
+	ctx, end := p.startSpan(ctx, "WaitForChange", changeAttributes(id))
+	defer end(&err)
+
+	return p.waitForChange(ctx, changeID(id))
+}
+
+// WaitForChanges waits concurrently for all the given changes to be INSYNC.
+// It returns the errors of all waits that failed, joined.
+func (p *Provider) WaitForChanges(ctx context.Context, changes ...Change) (err error) {
+	ctx, end := p.startSpan(ctx, "WaitForChanges", changeCountAttributes(changes))
+	defer end(&err)
+
+	return p.waitForChanges(ctx, changes)
+}
+
+// deferSync prepares a record method call for Route53SyncOncePerCall: the
+// returned context makes applyChange collect the changes it would wait for
+// instead of waiting, and the returned function waits for all of them
+// concurrently. It must be called once every change has been submitted.
+func (p *Provider) deferSync(ctx context.Context) (context.Context, func() error) {
+	if !p.Route53SyncOncePerCall {
+		return ctx, func() error { return nil }
+	}
+
+	deferred := &ChangeRecorder{}
+	ctx = context.WithValue(ctx, contextKeyDeferredSync, deferred)
+	return ctx, func() error {
+		changes := deferred.Changes()
+		if len(changes) == 0 {
+			return nil
+		}
+		p.Logger.DebugContext(ctx, "waiting for Route53 sync of all changes in call",
+			"change_count", len(changes))
+		errs := p.waitForEachChange(ctx, changes)
+
+		// the changes were reported to the caller's recorder when submitted,
+		// still PENDING
+		if r, ok := ctx.Value(contextKeyChangeRecorder).(*ChangeRecorder); ok && r != nil {
+			for i, c := range changes {
+				if errs[i] == nil {
+					r.setStatus(c.ID, ChangeStatusInSync)
+				}
+			}
+		}
+		return errors.Join(errs...)
+	}
+}
+
+// waitForChanges waits concurrently for all changes to be INSYNC.
+func (p *Provider) waitForChanges(ctx context.Context, changes []Change) error {
+	return errors.Join(p.waitForEachChange(ctx, changes)...)
+}
+
+// waitForEachChange waits concurrently for all changes to be INSYNC and
+// returns the error of each wait.
+func (p *Provider) waitForEachChange(ctx context.Context, changes []Change) []error {
+	errs := make([]error, len(changes))
+	var wg sync.WaitGroup
+	for i, c := range changes {
+		wg.Go(func() {
+			errs[i] = p.waitForChange(ctx, changeID(c.ID))
+		})
+	}
+	wg.Wait()
+	return errs
+}
+
+// waitForChange waits for the RecordSetChange status to be INSYNC, in a span
+// and measuring the time spent.
+func (p *Provider) waitForChange(ctx context.Context, id string) error {
+	ctx, span := p.telemetry.start(ctx, "route53.WaitForSync", attributeChangeID.String(id))
+	defer span.End()
+
+	start := time.Now()
+	err := p.pollChange(ctx, id)
+	waited := time.Since(start)
+	p.telemetry.recordSyncWait(ctx, waited, err)
+	p.metrics().ObserveSyncWait(waited, err == nil)
+	recordSpanError(span, err)
+	return err
+}
+
+// pollChange waits for the RecordSetChange status to be INSYNC, polling
+// GetChange with the configured Route53Sync* delays.
+func (p *Provider) pollChange(ctx context.Context, id string) error {
+	p.Logger.DebugContext(ctx, "waiting for Route53 sync",
+		"change_id", id,
+		"max_wait", p.Route53MaxWait,
+		"min_delay", p.Route53SyncMinDelay,
+		"max_delay", p.Route53SyncMaxDelay)
+
+	// Spread out the polls of changes submitted at the same time, so that
+	// bulk operations do not poll in lockstep against the API quota.
+	if p.Route53SyncJitter > 0 {
+		jitter := rand.N(p.Route53SyncJitter) //nolint:gosec // jitter does not need a secure source
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(jitter):
+		}
+	}
+
+	attempt := 0
+	waiter := r53.NewResourceRecordSetsChangedWaiter(p.client, func(o *r53.ResourceRecordSetsChangedWaiterOptions) {
+		o.MinDelay = p.Route53SyncMinDelay
+		o.MaxDelay = p.Route53SyncMaxDelay
+
+		retryable := o.Retryable
+		o.Retryable = func(
+			ctx context.Context,
+			input *r53.GetChangeInput,
+			output *r53.GetChangeOutput,
+			err error,
+		) (bool, error) {
+			attempt++
+			status := ""
+			if output != nil && output.ChangeInfo != nil {
+				status = string(output.ChangeInfo.Status)
+			}
+			p.Logger.DebugContext(ctx, "polled Route53 change status",
+				"change_id", id, "attempt", attempt, "status", status, "error", err)
+			return retryable(ctx, input, output, err)
+		}
+	})
+	if err := waiter.Wait(ctx, &r53.GetChangeInput{Id: aws.String(id)}, p.Route53MaxWait); err != nil {
+		return fmt.Errorf("waiting for change %s: %w", id, err)
+	}
+
+	p.Logger.DebugContext(ctx, "Route53 sync complete", "change_id", id, "attempts", attempt)
+	return nil
+}
+
+// changeID normalizes a change ID to the "/change/ID" form returned by
+// ChangeResourceRecordSets.
+func changeID(id string) string {
+	if strings.HasPrefix(id, "/change/") {
+		return id
+	}
+	return "/change/" + id
+}
@@ -0,0 +1,201 @@
+package route53 //nolint:testpackage // Testing internal functions
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/route53/types"
+	"github.com/libdns/libdns"
+)
+
+const (
+	pendingChangeResponse = `<GetChangeResponse xmlns="https://route53.amazonaws.com/doc/2013-04-01/">` +
+		`<ChangeInfo><Id>/change/C1</Id><Status>PENDING</Status>` +
+		`<SubmittedAt>2024-01-02T03:04:05Z</SubmittedAt></ChangeInfo></GetChangeResponse>`
+	noSuchChangeResponse = `<ErrorResponse><Error><Type>Sender</Type><Code>NoSuchChange</Code>` +
+		`<Message>no such change</Message></Error><RequestId>req-1</RequestId></ErrorResponse>`
+)
+
+func TestChangeID(t *testing.T) {
+	cases := []struct {
+		input    string
+		expected string
+	}{
+		{input: "C2682N5HXP0BZ4", expected: "/change/C2682N5HXP0BZ4"},
+		{input: "/change/C2682N5HXP0BZ4", expected: "/change/C2682N5HXP0BZ4"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.input, func(t *testing.T) {
+			if actual := changeID(c.input); actual != c.expected {
+				t.Errorf("expected %q, got %q", c.expected, actual)
+			}
+		})
+	}
+}
+
+func TestChangeRecorder(t *testing.T) {
+	submitted := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	change := changeFromInfo(&types.ChangeInfo{
+		Id:          aws.String("/change/C1"),
+		Status:      types.ChangeStatusPending,
+		SubmittedAt: aws.Time(submitted),
+	})
+
+	// recording without a recorder in the context is a no-op
+	recordChange(context.Background(), change)
+
+	var recorder ChangeRecorder
+	ctx := WithChangeRecorder(context.Background(), &recorder)
+	recordChange(ctx, change)
+	recordChange(ctx, Change{ID: "/change/C2", Status: ChangeStatusInSync})
+
+	changes := recorder.Changes()
+	if len(changes) != 2 {
+		t.Fatalf("expected 2 changes, got %d", len(changes))
+	}
+	if changes[0].ID != "/change/C1" || changes[0].Status != ChangeStatusPending ||
+		!changes[0].SubmittedAt.Equal(submitted) {
+		t.Errorf("unexpected first change: %+v", changes[0])
+	}
+	if changes[1].ID != "/change/C2" || changes[1].Status != ChangeStatusInSync {
+		t.Errorf("unexpected second change: %+v", changes[1])
+	}
+}
+
+func TestDeferSync(t *testing.T) {
+	for _, oncePerCall := range []bool{false, true} {
+		provider := &Provider{WaitForRoute53Sync: true, Route53SyncOncePerCall: oncePerCall}
+		provider.init(context.TODO())
+		ctx, wait := provider.deferSync(context.Background())
+		_, deferred := ctx.Value(contextKeyDeferredSync).(*ChangeRecorder)
+		if deferred != oncePerCall {
+			t.Errorf("Route53SyncOncePerCall=%v: expected deferred=%v, got %v", oncePerCall, oncePerCall, deferred)
+		}
+		// nothing was submitted, so there is nothing to wait for
+		if err := wait(); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+}
+
+func TestChangeStatus(t *testing.T) {
+	provider := &Provider{}
+	newCannedProvider(t, provider,
+		cannedResponse{status: http.StatusOK, body: getChangeResponse},
+		cannedResponse{status: http.StatusNotFound, body: noSuchChangeResponse},
+	)
+
+	change, err := provider.ChangeStatus(context.Background(), "C1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if change.ID != "/change/C1" || change.Status != ChangeStatusInSync {
+		t.Errorf("unexpected change %+v", change)
+	}
+
+	if _, err = provider.ChangeStatus(context.Background(), "C2"); err == nil ||
+		!strings.HasPrefix(err.Error(), "NoSuchChange") {
+		t.Errorf("expected a NoSuchChange error, got %v", err)
+	}
+}
+
+func TestWaitForChange(t *testing.T) {
+	provider := &Provider{Route53SyncMinDelay: time.Millisecond, Route53SyncMaxDelay: time.Millisecond}
+	canned := newCannedProvider(t, provider,
+		cannedResponse{status: http.StatusOK, body: pendingChangeResponse},
+		cannedResponse{status: http.StatusOK, body: getChangeResponse},
+	)
+
+	if err := provider.WaitForChange(context.Background(), "C1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(canned.requests) != 2 {
+		t.Errorf("expected to poll until INSYNC, got %d requests", len(canned.requests))
+	}
+	if path := canned.requests[0].URL.Path; path != "/2013-04-01/change/C1" {
+		t.Errorf("expected the change ID normalized, got %s", path)
+	}
+}
+
+func TestWaitForChanges(t *testing.T) {
+	provider := &Provider{Route53SyncMinDelay: time.Millisecond, Route53SyncMaxDelay: time.Millisecond}
+	canned := newCannedProvider(t, provider,
+		cannedResponse{status: http.StatusOK, body: getChangeResponse},
+		cannedResponse{status: http.StatusOK, body: getChangeResponse},
+	)
+
+	err := provider.WaitForChanges(context.Background(), Change{ID: "/change/C1"}, Change{ID: "C2"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(canned.requests) != 2 {
+		t.Errorf("expected each change polled, got %d requests", len(canned.requests))
+	}
+
+	// the errors of the failed waits are returned
+	newCannedProvider(t, provider, cannedResponse{status: http.StatusOK, body: getChangeResponse})
+	if err = provider.WaitForChanges(context.Background(), Change{ID: "C1"}, Change{ID: "C2"}); err == nil {
+		t.Error("expected the wait without a response to fail")
+	}
+}
+
+func TestApplyChangeRecorder(t *testing.T) {
+	provider := &Provider{HostedZoneID: "Z1"}
+	newCannedProvider(t, provider,
+		cannedResponse{status: http.StatusOK, body: listRecordSetsResponse()},
+		cannedResponse{status: http.StatusOK, body: changeResourceRecordSetsResponse},
+	)
+
+	var recorder ChangeRecorder
+	ctx := WithChangeRecorder(context.Background(), &recorder)
+	record := libdns.TXT{Name: "_acme-challenge", TTL: time.Minute, Text: "token"}
+	if _, err := provider.AppendRecords(ctx, "example.com.", []libdns.Record{record}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	changes := recorder.Changes()
+	if len(changes) != 1 {
+		t.Fatalf("expected 1 change recorded, got %d", len(changes))
+	}
+	change := changes[0]
+	if change.ID != "/change/C1" || change.Status != ChangeStatusPending ||
+		change.Zone != "example.com." || strings.TrimPrefix(change.HostedZoneID, hostedZonePrefix) != "Z1" {
+		t.Errorf("unexpected change %+v", change)
+	}
+	if len(change.RecordSets) != 1 || change.RecordSets[0].Name != "_acme-challenge" ||
+		len(change.RecordSets[0].Before) != 0 || len(change.RecordSets[0].After) != 1 {
+		t.Errorf("unexpected record sets %+v", change.RecordSets)
+	}
+}
+
+func TestDeferSyncUpdatesRecorder(t *testing.T) {
+	provider := &Provider{
+		HostedZoneID:           "Z1",
+		WaitForRoute53Sync:     true,
+		Route53SyncOncePerCall: true,
+		Route53SyncMinDelay:    time.Millisecond,
+		Route53SyncMaxDelay:    time.Millisecond,
+	}
+	newCannedProvider(t, provider,
+		cannedResponse{status: http.StatusOK, body: listRecordSetsResponse()},
+		cannedResponse{status: http.StatusOK, body: changeResourceRecordSetsResponse},
+		cannedResponse{status: http.StatusOK, body: getChangeResponse},
+	)
+
+	var recorder ChangeRecorder
+	ctx := WithChangeRecorder(context.Background(), &recorder)
+	record := libdns.TXT{Name: "_acme-challenge", TTL: time.Minute, Text: "token"}
+	if _, err := provider.AppendRecords(ctx, "example.com.", []libdns.Record{record}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	changes := recorder.Changes()
+	if len(changes) != 1 || changes[0].Status != ChangeStatusInSync {
+		t.Errorf("expected the recorded change INSYNC after the deferred wait, got %+v", changes)
+	}
+}
@@ -1,11 +1,13 @@
 package route53
 
 import (
+	"cmp"
 	"context"
 	"errors"
 	"fmt"
 	"log"
 	"log/slog"
+	"slices"
 	"strings"
 	"sync"
 	"time"
@@ -25,9 +27,23 @@ type setLockKey struct {
 	zoneID, name, recordType string
 }
 
-// lockSet acquires the per-tuple mutex and returns a function to release it.
+// setLock is the lock of a setLockKey: a token in sem means it is held.
+type setLock struct {
+	sem chan struct{}
+	// users counts the callers holding or waiting for the lock, and idle
+	// is when it last dropped to zero; both guarded by Provider.setLocksMu.
+	users int
+	idle  time.Time
+}
+
+// lockSet acquires the per-tuple lock and returns a function to release it.
 // Distinct tuples parallelize; concurrent callers on the same tuple serialize.
 //
+// Waiting for the lock stops when ctx is done, returning ctx.Err(), so a
+// hung change on a tuple does not block the callers that gave up on it.
+// Callers waiting for the lock are counted by Metrics.AddLockWaiters, and
+// their wait is logged.
+//
 // Holding the lock from getRecords through the ChangeResourceRecordSets call
 // closes the read-modify-write window — without it, two callers can both
 // observe pre-state and the later UPSERT clobbers the earlier (libdns
@@ -35,25 +51,127 @@ type setLockKey struct {
 // when SAN certificates share a _acme-challenge RecordSet).
 //
 // The lock map grows with unique tuples touched by this Provider — bounded
-// by the number of (name, type) pairs in the zones it manages, which is
-// negligible in practice. Multi-process coordination is out of scope; users
-// running multiple processes against the same zone must coordinate
-// externally.
-func (p *Provider) lockSet(k setLockKey) func() {
-	actual, _ := p.setLocks.LoadOrStore(k, &sync.Mutex{})
-	mu, ok := actual.(*sync.Mutex)
+// by the number of (name, type) pairs in the zones it manages, unless
+// SetLockIdleTimeout evicts the idle ones. Multi-process coordination is out
+// of scope; users running multiple processes against the same zone must
+// coordinate externally.
+func (p *Provider) lockSet(ctx context.Context, k setLockKey) (func(), error) {
+	lock := p.acquireSetLock(k)
+
+	select {
+	case lock.sem <- struct{}{}:
+	default:
+		p.metrics().AddLockWaiters(1)
+		start := time.Now()
+		select {
+		case lock.sem <- struct{}{}:
+		case <-ctx.Done():
+			p.metrics().AddLockWaiters(-1)
+			p.releaseSetLock(lock)
+			p.Logger.DebugContext(ctx, "gave up waiting for record set lock",
+				"zone_id", k.zoneID, "name", k.name, "type", k.recordType, "waited", time.Since(start))
+			return nil, ctx.Err()
+		}
+		p.metrics().AddLockWaiters(-1)
+		p.Logger.DebugContext(ctx, "waited for record set lock",
+			"zone_id", k.zoneID, "name", k.name, "type", k.recordType, "waited", time.Since(start))
+	}
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			<-lock.sem
+			p.releaseSetLock(lock)
+		})
+	}, nil
+}
+
+// lockSets takes the per-tuple locks of several record sets of a hosted zone,
+// in a fixed order so concurrent callers locking overlapping record sets
+// cannot deadlock. The returned function releases them all.
+func (p *Provider) lockSets(ctx context.Context, zoneID string, keys []recordSetKey) (func(), error) {
+	keys = slices.Clone(keys)
+	slices.SortFunc(keys, func(a, b recordSetKey) int {
+		return cmp.Or(strings.Compare(a.name, b.name), strings.Compare(a.recordType, b.recordType))
+	})
+	keys = slices.Compact(keys)
+
+	unlocks := make([]func(), 0, len(keys))
+	unlockAll := func() {
+		for _, unlock := range unlocks {
+			unlock()
+		}
+	}
+	for _, key := range keys {
+		unlock, err := p.lockSet(ctx, setLockKey{zoneID: zoneID, name: key.name, recordType: key.recordType})
+		if err != nil {
+			unlockAll()
+			return nil, err
+		}
+		unlocks = append(unlocks, unlock)
+	}
+	return unlockAll, nil
+}
+
+// acquireSetLock returns the lock of a tuple, counting the caller as one of
+// its users so it is not evicted.
+func (p *Provider) acquireSetLock(k setLockKey) *setLock {
+	p.setLocksMu.Lock()
+	defer p.setLocksMu.Unlock()
+
+	if p.setLocks == nil {
+		p.setLocks = make(map[setLockKey]*setLock)
+	}
+	p.evictIdleSetLocks()
+
+	lock, ok := p.setLocks[k]
 	if !ok {
-		// unreachable: setLocks only ever stores *sync.Mutex.
-		panic("route53: setLocks contains non-*sync.Mutex value")
+		lock = &setLock{sem: make(chan struct{}, 1)}
+		p.setLocks[k] = lock
+	}
+	lock.users++
+	return lock
+}
+
+// releaseSetLock stops counting the caller as a user of a lock.
+func (p *Provider) releaseSetLock(lock *setLock) {
+	p.setLocksMu.Lock()
+	defer p.setLocksMu.Unlock()
+
+	lock.users--
+	if lock.users == 0 {
+		lock.idle = time.Now()
 	}
-	mu.Lock()
-	return mu.Unlock
 }
 
+// evictIdleSetLocks removes the locks unused for SetLockIdleTimeout, at most
+// once per SetLockIdleTimeout. It must be called with setLocksMu held.
+func (p *Provider) evictIdleSetLocks() {
+	if p.SetLockIdleTimeout <= 0 || time.Since(p.setLocksSwept) < p.SetLockIdleTimeout {
+		return
+	}
+	p.setLocksSwept = time.Now()
+
+	for k, lock := range p.setLocks {
+		if lock.users == 0 && time.Since(lock.idle) >= p.SetLockIdleTimeout {
+			delete(p.setLocks, k)
+		}
+	}
+}
+
+// ErrHostedZoneNotFound is returned when no hosted zone matches a zone name.
+var ErrHostedZoneNotFound = errors.New("HostedZoneNotFound")
+
 type contextKey int
 
 const (
-	contextKeyIsDeleteOperation contextKey = iota
+	contextKeyOperation contextKey = iota
+	contextKeySyncWait
+	contextKeyChangeRecorder
+	contextKeyDeferredSync
+	contextKeyJournalActor
+	contextKeyAPICallLog
+	contextKeyRecordCache
 )
 
 const (
@@ -63,38 +181,32 @@ const (
 	maxTXTValueLength = 255
 	// maxRecordsPerPage is the maximum number of records Route53 returns per page.
 	maxRecordsPerPage = 1000
+	// maxChangeRecords is the maximum number of ResourceRecord elements in a
+	// single ChangeResourceRecordSets request.
+	maxChangeRecords = 1000
+	// maxChangeChars is the maximum number of characters of record values in
+	// a single ChangeResourceRecordSets request.
+	maxChangeChars = 32000
 )
 
 // changeRecordSet performs a specified action (UPSERT or DELETE) on a ResourceRecordSet.
 func (p *Provider) changeRecordSet(
 	ctx context.Context,
 	zoneID, zone, name, recordType string,
-	records []libdns.Record,
+	before, records []libdns.Record,
 	action types.ChangeAction,
 ) error {
-	var resourceRecords []types.ResourceRecord
-	for _, record := range records {
-		rr := record.RR()
-		resourceRecords = append(resourceRecords, marshalRecord(rr)...)
-	}
-
-	// use the TTL from the first record
-	ttl := int64(defaultTTL)
-	if len(records) > 0 {
-		ttl = int64(records[0].RR().TTL.Seconds())
+	set, err := buildRecordSet(zone, name, recordType, records)
+	if err != nil {
+		return err
 	}
 
 	input := &r53.ChangeResourceRecordSetsInput{
 		ChangeBatch: &types.ChangeBatch{
 			Changes: []types.Change{
 				{
-					Action: action,
-					ResourceRecordSet: &types.ResourceRecordSet{
-						Name:            aws.String(libdns.AbsoluteName(name, zone)),
-						ResourceRecords: resourceRecords,
-						TTL:             aws.Int64(ttl),
-						Type:            types.RRType(recordType),
-					},
+					Action:            action,
+					ResourceRecordSet: set,
 				},
 			},
 		},
@@ -106,28 +218,74 @@ func (p *Provider) changeRecordSet(
 		"zone", zone,
 		"name", name,
 		"type", recordType,
-		"value_count", len(resourceRecords),
-		"ttl_seconds", ttl)
+		"value_count", len(set.ResourceRecords),
+		"ttl_seconds", aws.ToInt64(set.TTL),
+		"alias", set.AliasTarget != nil)
+
+	after := records
+	if action == types.ChangeActionDelete {
+		after = nil
+	}
+	sets := []RecordSetChange{{Name: name, Type: recordType, Before: before, After: after}}
+
+	_, err = p.applyChange(ctx, zone, input, sets)
+	return err
+}
+
+// buildRecordSet assembles the ResourceRecordSet holding records. An alias
+// record set holds a single Alias record and nothing else.
+func buildRecordSet(zone, name, recordType string, records []libdns.Record) (*types.ResourceRecordSet, error) {
+	set := &types.ResourceRecordSet{
+		Name: aws.String(libdns.AbsoluteName(name, zone)),
+		Type: types.RRType(recordType),
+	}
+
+	for _, record := range records {
+		if alias, ok := asAlias(record); ok {
+			if len(records) != 1 {
+				return nil, fmt.Errorf("alias record %s %s cannot be combined with other values", name, recordType)
+			}
+			set.AliasTarget = alias.target()
+			return set, nil
+		}
+	}
+
+	var resourceRecords []types.ResourceRecord
+	for _, record := range records {
+		rr := record.RR()
+		resourceRecords = append(resourceRecords, marshalRecord(rr)...)
+	}
+
+	// use the TTL from the first record
+	ttl := int64(defaultTTL)
+	if len(records) > 0 {
+		ttl = int64(records[0].RR().TTL.Seconds())
+	}
 
-	return p.applyChange(ctx, input)
+	set.ResourceRecords = resourceRecords
+	set.TTL = aws.Int64(ttl)
+	return set, nil
 }
 
+// setRecordSet replaces the record set, whose current values are before,
+// with records.
 func (p *Provider) setRecordSet(
 	ctx context.Context,
 	zoneID, zone, name, recordType string,
-	records []libdns.Record,
+	before, records []libdns.Record,
 ) error {
 	// use UPSERT to replace the entire record set
-	return p.changeRecordSet(ctx, zoneID, zone, name, recordType, records, types.ChangeActionUpsert)
+	return p.changeRecordSet(ctx, zoneID, zone, name, recordType, before, records, types.ChangeActionUpsert)
 }
 
+// deleteRecordSet deletes the record set, whose current values are records.
 func (p *Provider) deleteRecordSet(
 	ctx context.Context,
 	zoneID, zone, name, recordType string,
 	records []libdns.Record,
 ) error {
 	// use DELETE action to remove the entire record set
-	return p.changeRecordSet(ctx, zoneID, zone, name, recordType, records, types.ChangeActionDelete)
+	return p.changeRecordSet(ctx, zoneID, zone, name, recordType, records, records, types.ChangeActionDelete)
 }
 
 func (p *Provider) init(ctx context.Context) {
@@ -139,6 +297,12 @@ func (p *Provider) init(ctx context.Context) {
 	}
 
 	p.initOnce.Do(func() {
+		p.initTelemetry(ctx)
+
+		if p.Journal == nil && p.JournalFile != "" {
+			p.Journal = NewFileJournal(p.JournalFile)
+		}
+
 		if p.MaxRetries == 0 {
 			p.MaxRetries = 5
 		}
@@ -147,6 +311,26 @@ func (p *Provider) init(ctx context.Context) {
 			p.Route53MaxWait = time.Minute
 		}
 
+		if p.Route53SyncMinDelay == 0 {
+			p.Route53SyncMinDelay = defaultRoute53SyncMinDelay
+		}
+
+		if p.Route53SyncMaxDelay == 0 {
+			p.Route53SyncMaxDelay = defaultRoute53SyncMaxDelay
+		}
+
+		if p.Route53SyncMaxDelay < p.Route53SyncMinDelay {
+			p.Route53SyncMaxDelay = p.Route53SyncMinDelay
+		}
+
+		if p.DNSPropagationTimeout == 0 {
+			p.DNSPropagationTimeout = defaultDNSPropagationTimeout
+		}
+
+		if p.DNSPropagationInterval == 0 {
+			p.DNSPropagationInterval = defaultDNSPropagationInterval
+		}
+
 		opts := make([]func(*config.LoadOptions) error, 0)
 		opts = append(opts,
 			config.WithRetryer(func() aws.Retryer {
@@ -180,7 +364,9 @@ func (p *Provider) init(ctx context.Context) {
 			log.Fatalf("route53: unable to load AWS SDK config, %v", err)
 		}
 
-		p.client = r53.NewFromConfig(cfg)
+		p.client = r53.NewFromConfig(cfg, func(o *r53.Options) {
+			o.APIOptions = append(o.APIOptions, p.addTelemetryMiddleware, p.addLoggingMiddleware)
+		})
 	})
 }
 
@@ -209,6 +395,11 @@ func parseRecordSet(set types.ResourceRecordSet, zone string) ([]libdns.Record,
 	rtype := string(set.Type)
 	relativeName := libdns.RelativeName(*set.Name, zone)
 
+	// Alias record sets have no values of their own
+	if set.AliasTarget != nil {
+		return append(records, aliasFromTarget(relativeName, rtype, set.AliasTarget)), nil
+	}
+
 	for _, record := range set.ResourceRecords {
 		value := *record.Value
 		switch rtype {
@@ -287,55 +478,111 @@ func marshalRecord(record libdns.RR) []types.ResourceRecord {
 }
 
 func (p *Provider) getRecords(ctx context.Context, zoneID string, zone string) ([]libdns.Record, error) {
-	getRecordsInput := &r53.ListResourceRecordSetsInput{
-		HostedZoneId: aws.String(zoneID),
-		MaxItems:     aws.Int32(maxRecordsPerPage),
+	sets, err := p.listRecordSets(ctx, zoneID)
+	if err != nil {
+		return nil, err
 	}
 
 	var records []libdns.Record
+	for _, s := range sets {
+		parsedRecords, parseErr := parseRecordSet(s, zone)
+		if parseErr != nil {
+			return records, fmt.Errorf("failed to parse record set: %w", parseErr)
+		}
+		records = append(records, parsedRecords...)
+	}
+
+	return records, nil
+}
+
+// listRecordSets returns every ResourceRecordSet of the hosted zone as
+// Route53 returns them, including alias and routing policy fields.
+//
+// With RecordCacheTTL, the listings of the record methods come from the
+// cache while it is fresh; see withRecordCache.
+func (p *Provider) listRecordSets(ctx context.Context, zoneID string) ([]types.ResourceRecordSet, error) {
+	cached := usesRecordCache(ctx)
+	var version uint64
+	if cached {
+		sets, listedVersion, ok := p.recordCache.get(zoneID)
+		if ok {
+			p.Logger.DebugContext(ctx, "using cached record sets", "hosted_zone_id", zoneID, "record_sets", len(sets))
+			return sets, nil
+		}
+		version = listedVersion
+	}
+
+	var sets []types.ResourceRecordSet
+	err := p.scanRecordSets(ctx, &r53.ListResourceRecordSetsInput{HostedZoneId: aws.String(zoneID)},
+		func(set types.ResourceRecordSet) bool {
+			sets = append(sets, set)
+			return true
+		})
+	if err != nil {
+		return sets, err
+	}
+
+	if cached {
+		p.recordCache.put(zoneID, version, sets, p.RecordCacheTTL)
+	}
+	return sets, nil
+}
+
+// scanRecordSets lists the ResourceRecordSets of a hosted zone page by page,
+// from input.StartRecordName if set, calling fn for each of them until it
+// returns false. Route53 lists them sorted by name with the labels reversed,
+// as in com.example.www., and then by type.
+func (p *Provider) scanRecordSets(
+	ctx context.Context,
+	input *r53.ListResourceRecordSetsInput,
+	fn func(set types.ResourceRecordSet) bool,
+) error {
+	if input.MaxItems == nil {
+		input.MaxItems = aws.Int32(maxRecordsPerPage)
+	}
 
 	for {
-		getRecordResult, err := p.client.ListResourceRecordSets(ctx, getRecordsInput)
+		getRecordResult, err := p.client.ListResourceRecordSets(ctx, input)
 		if err != nil {
 			var nshze *types.NoSuchHostedZone
 			var iie *types.InvalidInput
 			switch {
 			case errors.As(err, &nshze):
-				return records, fmt.Errorf("NoSuchHostedZone: %w", err)
+				return fmt.Errorf("NoSuchHostedZone: %w", err)
 			case errors.As(err, &iie):
-				return records, fmt.Errorf("InvalidInput: %w", err)
+				return fmt.Errorf("InvalidInput: %w", err)
 			default:
-				return records, err
+				return err
 			}
 		}
 
-		for _, s := range getRecordResult.ResourceRecordSets {
-			parsedRecords, parseErr := parseRecordSet(s, zone)
-			if parseErr != nil {
-				return records, fmt.Errorf("failed to parse record set: %w", parseErr)
+		for _, set := range getRecordResult.ResourceRecordSets {
+			if !fn(set) {
+				return nil
 			}
-			records = append(records, parsedRecords...)
 		}
 
-		if getRecordResult.IsTruncated {
-			getRecordsInput.StartRecordName = getRecordResult.NextRecordName
-			getRecordsInput.StartRecordType = getRecordResult.NextRecordType
-			getRecordsInput.StartRecordIdentifier = getRecordResult.NextRecordIdentifier
-		} else {
-			break
+		if !getRecordResult.IsTruncated {
+			return nil
 		}
+		input.StartRecordName = getRecordResult.NextRecordName
+		input.StartRecordType = getRecordResult.NextRecordType
+		input.StartRecordIdentifier = getRecordResult.NextRecordIdentifier
 	}
-
-	return records, nil
 }
 
 func (p *Provider) getZoneID(ctx context.Context, zoneName string) (string, error) {
 	if p.HostedZoneID != "" {
 		p.Logger.DebugContext(ctx, "using preconfigured hosted zone id",
 			"zone", zoneName, "hosted_zone_id", p.HostedZoneID)
-		return "/hostedzone/" + p.HostedZoneID, nil
+		return hostedZonePrefix + p.HostedZoneID, nil
 	}
 
+	return p.lookupZoneID(ctx, zoneName)
+}
+
+// lookupZoneID finds the hosted zone ID by name, ignoring HostedZoneID.
+func (p *Provider) lookupZoneID(ctx context.Context, zoneName string) (string, error) {
 	getZoneInput := &r53.ListHostedZonesByNameInput{
 		DNSName:  aws.String(zoneName),
 		MaxItems: aws.Int32(1),
@@ -388,52 +635,162 @@ func (p *Provider) getZoneID(ctx context.Context, zoneName string) (string, erro
 		return *matchingZones[0].Id, nil
 	}
 
-	return "", fmt.Errorf("HostedZoneNotFound: No zones found for the domain %s", zoneName)
+	return "", fmt.Errorf("%w: No zones found for the domain %s", ErrHostedZoneNotFound, zoneName)
 }
 
-func (p *Provider) applyChange(ctx context.Context, input *r53.ChangeResourceRecordSetsInput) error {
+// applyChange submits a change batch to the hosted zone of zone, reports it
+// to the Journal along with sets, the record sets it modifies, and settles
+// it.
+func (p *Provider) applyChange(
+	ctx context.Context,
+	zone string,
+	input *r53.ChangeResourceRecordSetsInput,
+	sets []RecordSetChange,
+) (Change, error) {
 	changeResult, err := p.client.ChangeResourceRecordSets(ctx, input)
 	if err != nil {
-		return err
+		// the change may have been applied anyway, for example on a timeout
+		p.recordCache.invalidate(aws.ToString(input.HostedZoneId))
+		return Change{}, err
 	}
 
-	changeID := aws.ToString(changeResult.ChangeInfo.Id)
-	p.Logger.DebugContext(ctx, "Route53 change submitted",
-		"change_id", changeID,
-		"status", string(changeResult.ChangeInfo.Status))
+	p.recordCache.apply(aws.ToString(input.HostedZoneId), input.ChangeBatch.Changes)
+	p.countChanges(input.ChangeBatch.Changes)
 
-	// Check if we should skip waiting for synchronization
-	shouldWait := p.WaitForRoute53Sync
-	skippedForDelete := false
-	if shouldWait && p.SkipRoute53SyncOnDelete {
-		// Check if this is a delete operation
-		if isDelete, ok := ctx.Value(contextKeyIsDeleteOperation).(bool); ok && isDelete {
-			shouldWait = false
-			skippedForDelete = true
+	change := changeFromInfo(changeResult.ChangeInfo)
+	change.Zone = zone
+	change.HostedZoneID = aws.ToString(input.HostedZoneId)
+	change.RecordSets = sets
+	p.Logger.DebugContext(ctx, "Route53 change submitted",
+		"change_id", change.ID,
+		"status", change.Status)
+
+	p.journal(ctx, change)
+
+	// Check if we should wait for synchronization, see SyncPolicy. A batch
+	// is waited for if any of its record sets requires it.
+	shouldWait := false
+	var recordType string
+	for _, c := range input.ChangeBatch.Changes {
+		if c.ResourceRecordSet == nil {
+			continue
+		}
+		recordType = string(c.ResourceRecordSet.Type)
+		if p.shouldWaitForSync(ctx, recordType) {
+			shouldWait = true
+			break
 		}
 	}
 
-	// Wait for propagation if enabled and not skipped
-	if shouldWait {
-		changeInput := &r53.GetChangeInput{
-			Id: changeResult.ChangeInfo.Id,
+	return p.settleChange(ctx, change, shouldWait, recordType)
+}
+
+// settleChange waits for a submitted change if shouldWait is set — or defers
+// the wait with Route53SyncOncePerCall — and reports the change to the
+// context's ChangeRecorder.
+func (p *Provider) settleChange(
+	ctx context.Context,
+	change Change,
+	shouldWait bool,
+	recordType string,
+) (Change, error) {
+	var err error
+
+	// Wait for propagation if enabled and not skipped. With
+	// Route53SyncOncePerCall the record method waits for all its changes
+	// at once after submitting the last one.
+	deferred, isDeferred := ctx.Value(contextKeyDeferredSync).(*ChangeRecorder)
+	switch {
+	case shouldWait && isDeferred:
+		deferred.record(change)
+		p.Logger.DebugContext(ctx, "deferring Route53 sync wait until all changes are submitted",
+			"change_id", change.ID)
+	case shouldWait:
+		err = p.waitForChange(ctx, change.ID)
+		if err == nil {
+			change.Status = ChangeStatusInSync
 		}
+	case p.WaitForRoute53Sync:
+		operation, _ := ctx.Value(contextKeyOperation).(string)
+		p.Logger.DebugContext(ctx, "skipping Route53 sync wait",
+			"change_id", change.ID, "operation", operation, "type", recordType)
+	}
 
-		p.Logger.DebugContext(ctx, "waiting for Route53 sync",
-			"change_id", changeID, "max_wait", p.Route53MaxWait)
+	// The change was submitted even if waiting for it failed, so callers
+	// tracking changes still get a handle they can retry the wait with.
+	recordChange(ctx, change)
 
-		// Wait for the RecordSetChange status to be "INSYNC"
-		waiter := r53.NewResourceRecordSetsChangedWaiter(p.client)
-		err = waiter.Wait(ctx, changeInput, p.Route53MaxWait)
+	return change, err
+}
+
+// submitChanges applies changes to the hosted zone in as few batches as the
+// Route53 limits allow, see batchChanges. Every method submitting several
+// record sets at once goes through it. sets describes each change for the
+// Journal, in the same order. Batches are applied in order, and it stops at
+// the first batch that fails. It returns the changes submitted so far.
+func (p *Provider) submitChanges(
+	ctx context.Context,
+	zoneID, zone string,
+	changes []types.Change,
+	sets []RecordSetChange,
+) ([]Change, error) {
+	var submitted []Change
+	offset := 0
+	for _, batch := range batchChanges(changes) {
+		p.Logger.DebugContext(ctx, "applying Route53 change batch",
+			"hosted_zone_id", zoneID, "change_count", len(batch))
+
+		batchSets := sets[offset : offset+len(batch)]
+		offset += len(batch)
+
+		change, err := p.applyChange(ctx, zone, &r53.ChangeResourceRecordSetsInput{
+			ChangeBatch:  &types.ChangeBatch{Changes: batch},
+			HostedZoneId: aws.String(zoneID),
+		}, batchSets)
+		if change.ID != "" {
+			submitted = append(submitted, change)
+		}
 		if err != nil {
-			return err
+			return submitted, err
 		}
+	}
+	return submitted, nil
+}
+
+// batchChanges splits changes into batches within the ChangeResourceRecordSets
+// limits: at most 1000 ResourceRecord elements and 32000 characters of values
+// per request, where UPSERT changes count twice.
+func batchChanges(changes []types.Change) [][]types.Change {
+	var batches [][]types.Change
+	var batch []types.Change
+	var records, chars int
+
+	for _, c := range changes {
+		weight := 1
+		if c.Action == types.ChangeActionUpsert {
+			weight = 2
+		}
+		cRecords, cChars := 1, 0
+		if set := c.ResourceRecordSet; set != nil && len(set.ResourceRecords) > 0 {
+			cRecords = len(set.ResourceRecords)
+			for _, rr := range set.ResourceRecords {
+				cChars += len(aws.ToString(rr.Value))
+			}
+		}
+		cRecords *= weight
+		cChars *= weight
 
-		p.Logger.DebugContext(ctx, "Route53 sync complete", "change_id", changeID)
-	} else if skippedForDelete {
-		p.Logger.DebugContext(ctx, "skipping Route53 sync wait for delete operation",
-			"change_id", changeID)
+		if len(batch) > 0 && (records+cRecords > maxChangeRecords || chars+cChars > maxChangeChars) {
+			batches = append(batches, batch)
+			batch, records, chars = nil, 0, 0
+		}
+		batch = append(batch, c)
+		records += cRecords
+		chars += cChars
+	}
+	if len(batch) > 0 {
+		batches = append(batches, batch)
 	}
 
-	return nil
+	return batches
 }
@@ -2,6 +2,10 @@ package route53 //nolint:testpackage // Testing internal functions
 
 import (
 	"context"
+	"errors"
+	"log/slog"
+	"net/netip"
+	"strings"
 	"testing"
 	"time"
 
@@ -207,6 +211,24 @@ func TestParseRecordSet(t *testing.T) { //nolint:gocognit // test complexity is
 				},
 			},
 		},
+		{
+			name: "alias record",
+			input: types.ResourceRecordSet{
+				Name: aws.String("cdn.example.com."),
+				Type: types.RRTypeA,
+				AliasTarget: &types.AliasTarget{
+					DNSName:      aws.String("d111111abcdef8.cloudfront.net."),
+					HostedZoneId: aws.String("Z2FDTNDATAQYW2"),
+				},
+			},
+			expected: []libdns.RR{
+				{
+					Type: "A",
+					Name: "cdn",
+					Data: "d111111abcdef8.cloudfront.net.",
+				},
+			},
+		},
 	}
 
 	for _, c := range cases {
@@ -391,3 +413,205 @@ func TestRoute53MaxWait(t *testing.T) {
 		})
 	}
 }
+
+func TestRoute53SyncDelays(t *testing.T) {
+	cases := []struct {
+		name        string
+		minDelay    time.Duration
+		maxDelay    time.Duration
+		expectedMin time.Duration
+		expectedMax time.Duration
+	}{
+		{
+			name:        "default",
+			expectedMin: 30 * time.Second,
+			expectedMax: 120 * time.Second,
+		},
+		{
+			name:        "custom",
+			minDelay:    2 * time.Second,
+			maxDelay:    10 * time.Second,
+			expectedMin: 2 * time.Second,
+			expectedMax: 10 * time.Second,
+		},
+		{
+			name:        "max below min",
+			minDelay:    5 * time.Second,
+			maxDelay:    time.Second,
+			expectedMin: 5 * time.Second,
+			expectedMax: 5 * time.Second,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			provider := Provider{Route53SyncMinDelay: c.minDelay, Route53SyncMaxDelay: c.maxDelay}
+			provider.init(context.TODO())
+			if provider.Route53SyncMinDelay != c.expectedMin || provider.Route53SyncMaxDelay != c.expectedMax {
+				t.Errorf("expected delays %v-%v, got %v-%v", c.expectedMin, c.expectedMax,
+					provider.Route53SyncMinDelay, provider.Route53SyncMaxDelay)
+			}
+		})
+	}
+}
+
+func TestBatchChanges(t *testing.T) {
+	change := func(action types.ChangeAction, values ...string) types.Change {
+		set := &types.ResourceRecordSet{Name: aws.String("test.example.com."), Type: types.RRTypeTxt}
+		for _, v := range values {
+			set.ResourceRecords = append(set.ResourceRecords, types.ResourceRecord{Value: aws.String(v)})
+		}
+		return types.Change{Action: action, ResourceRecordSet: set}
+	}
+
+	long := strings.Repeat("x", 10000)
+	cases := []struct {
+		name     string
+		changes  []types.Change
+		expected []int
+	}{
+		{name: "empty", expected: nil},
+		{
+			name:     "single batch",
+			changes:  []types.Change{change(types.ChangeActionDelete, "a"), change(types.ChangeActionUpsert, "b")},
+			expected: []int{2},
+		},
+		{
+			name: "value characters",
+			changes: []types.Change{
+				change(types.ChangeActionCreate, long, long),
+				change(types.ChangeActionCreate, long),
+				change(types.ChangeActionUpsert, long),
+			},
+			expected: []int{2, 1},
+		},
+	}
+
+	many := make([]types.Change, 0, 600)
+	for range 600 {
+		many = append(many, change(types.ChangeActionUpsert, "v"))
+	}
+	cases = append(cases, struct {
+		name     string
+		changes  []types.Change
+		expected []int
+	}{name: "upserts count twice", changes: many, expected: []int{500, 100}})
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			batches := batchChanges(c.changes)
+			if len(batches) != len(c.expected) {
+				t.Fatalf("expected %d batches, got %d", len(c.expected), len(batches))
+			}
+			for i, batch := range batches {
+				if len(batch) != c.expected[i] {
+					t.Errorf("batch %d: expected %d changes, got %d", i, c.expected[i], len(batch))
+				}
+			}
+		})
+	}
+}
+
+func TestBuildRecordSetAlias(t *testing.T) {
+	alias := Alias{
+		Name:                 "cdn",
+		Type:                 "A",
+		Target:               "d111111abcdef8.cloudfront.net.",
+		HostedZoneID:         "Z2FDTNDATAQYW2",
+		EvaluateTargetHealth: true,
+	}
+
+	set, err := buildRecordSet("example.com.", "cdn", "A", []libdns.Record{alias})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if set.AliasTarget == nil {
+		t.Fatal("expected an alias target")
+	}
+	if set.TTL != nil || len(set.ResourceRecords) != 0 {
+		t.Errorf("expected no TTL and no values, got TTL %v and %d values", set.TTL, len(set.ResourceRecords))
+	}
+	if got := aliasFromTarget("cdn", "A", set.AliasTarget); got != alias {
+		t.Errorf("expected %+v, got %+v", alias, got)
+	}
+
+	_, err = buildRecordSet("example.com.", "cdn", "A", []libdns.Record{
+		alias,
+		libdns.Address{Name: "cdn", IP: netip.MustParseAddr("192.0.2.1")},
+	})
+	if err == nil {
+		t.Error("expected an error when combining an alias with other values")
+	}
+}
+
+func TestLockSetContext(t *testing.T) {
+	provider := &Provider{Logger: slog.New(slog.DiscardHandler)}
+	key := setLockKey{zoneID: "Z1", name: "_acme-challenge", recordType: "TXT"}
+
+	unlock, err := provider.lockSet(context.Background(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err = provider.lockSet(ctx, key); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the wait to stop with the context, got %v", err)
+	}
+
+	unlock()
+	unlock() // releasing twice is harmless
+
+	// the abandoned wait does not hold the lock
+	unlock, err = provider.lockSet(context.Background(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unlock()
+	if users := provider.setLocks[key].users; users != 0 {
+		t.Errorf("expected no users left, got %d", users)
+	}
+}
+
+func TestEvictIdleSetLocks(t *testing.T) {
+	provider := &Provider{Logger: slog.New(slog.DiscardHandler), SetLockIdleTimeout: time.Millisecond}
+	idle := setLockKey{zoneID: "Z1", name: "_acme-challenge.a", recordType: "TXT"}
+	held := setLockKey{zoneID: "Z1", name: "_acme-challenge.b", recordType: "TXT"}
+
+	unlock, err := provider.lockSet(context.Background(), idle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unlock()
+	unlockHeld, err := provider.lockSet(context.Background(), held)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unlockHeld()
+
+	time.Sleep(5 * time.Millisecond)
+	unlock, err = provider.lockSet(context.Background(), setLockKey{zoneID: "Z1", name: "www", recordType: "A"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	unlock()
+
+	if _, ok := provider.setLocks[idle]; ok {
+		t.Error("expected the idle lock evicted")
+	}
+	if _, ok := provider.setLocks[held]; !ok {
+		t.Error("expected the held lock kept")
+	}
+
+	// without SetLockIdleTimeout locks are kept
+	provider = &Provider{Logger: slog.New(slog.DiscardHandler)}
+	for _, key := range []setLockKey{idle, held} {
+		if unlock, err = provider.lockSet(context.Background(), key); err != nil {
+			t.Fatal(err)
+		}
+		unlock()
+	}
+	if len(provider.setLocks) != 2 {
+		t.Errorf("expected 2 locks kept, got %d", len(provider.setLocks))
+	}
+}
@@ -0,0 +1,315 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/libdns/libdns"
+	"github.com/libdns/route53"
+)
+
+// env is what a command runs with.
+type env struct {
+	provider *route53.Provider
+	output   string
+	stdin    io.Reader
+	stdout   io.Writer
+	stderr   io.Writer
+}
+
+// command is a route53dns subcommand.
+type command struct {
+	args    string
+	summary string
+	run     func(ctx context.Context, e *env, args []string) error
+}
+
+// commandNames returns the command names in the order usage lists them.
+func commandNames() []string {
+	return []string{"zones", "get", "append", "set", "delete", "export", "import", "diff", "wait"}
+}
+
+// commands returns the commands by name.
+func commands() map[string]command {
+	return map[string]command{
+		"zones":  {args: "", summary: "list hosted zones", run: runZones},
+		"get":    {args: "<zone>", summary: "list the records of a zone", run: runGet},
+		"append": {args: "<zone> <record>...", summary: "add records", run: runAppend},
+		"set":    {args: "<zone> <record>...", summary: "replace record sets", run: runSet},
+		"delete": {args: "<zone> <record>...", summary: "delete records", run: runDelete},
+		"export": {args: "<zone>", summary: "write the zone as a BIND zone file", run: runExport},
+		"import": {args: "<zone> <file>", summary: "UPSERT the record sets of a zone file", run: runImport},
+		"diff":   {args: "<zone> <file|zone>", summary: "compare a zone with a zone file or another zone", run: runDiff},
+		"wait":   {args: "<change-id>...", summary: "wait for changes to be INSYNC", run: runWait},
+	}
+}
+
+// newFlagSet returns the flag set of a command.
+func newFlagSet(e *env, name string) *flag.FlagSet {
+	fs := flag.NewFlagSet("route53dns "+name, flag.ContinueOnError)
+	fs.SetOutput(e.stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(e.stderr, "Usage: route53dns %s [flags] %s\n", name, commands()[name].args)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parseArgs parses the flags of a command and checks it has between min and
+// max positional arguments; max < 0 means no limit.
+func parseArgs(fs *flag.FlagSet, args []string, minArgs, maxArgs int) error {
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() < minArgs || (maxArgs >= 0 && fs.NArg() > maxArgs) {
+		fs.Usage()
+		return errUsage
+	}
+	return nil
+}
+
+func runZones(ctx context.Context, e *env, args []string) error {
+	fs := newFlagSet(e, "zones")
+	if err := parseArgs(fs, args, 0, 0); err != nil {
+		return err
+	}
+
+	zones, err := e.provider.HostedZones(ctx)
+	if err != nil {
+		return err
+	}
+	return e.writeZones(zones)
+}
+
+func runGet(ctx context.Context, e *env, args []string) error {
+	fs := newFlagSet(e, "get")
+	name := fs.String("name", "", "only records with this relative `name`")
+	recordType := fs.String("type", "", "only records of this `type`")
+	if err := parseArgs(fs, args, 1, 1); err != nil {
+		return err
+	}
+
+	records, err := e.provider.GetRecords(ctx, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	records = slices.DeleteFunc(records, func(record libdns.Record) bool {
+		rr := record.RR()
+		return (*name != "" && !strings.EqualFold(rr.Name, *name)) ||
+			(*recordType != "" && !strings.EqualFold(rr.Type, *recordType))
+	})
+	return e.writeRecords(records)
+}
+
+func runAppend(ctx context.Context, e *env, args []string) error {
+	return e.runRecordMethod(ctx, "append", args, e.provider.AppendRecords)
+}
+
+func runSet(ctx context.Context, e *env, args []string) error {
+	return e.runRecordMethod(ctx, "set", args, e.provider.SetRecords)
+}
+
+func runDelete(ctx context.Context, e *env, args []string) error {
+	return e.runRecordMethod(ctx, "delete", args, e.provider.DeleteRecords)
+}
+
+// runRecordMethod runs one of the libdns record methods with the records
+// given as arguments or in a file, and prints the affected records and, with
+// -changes, the changes submitted.
+func (e *env) runRecordMethod(
+	ctx context.Context,
+	name string,
+	args []string,
+	method func(context.Context, string, []libdns.Record) ([]libdns.Record, error),
+) error {
+	fs := newFlagSet(e, name)
+	file := fs.String("f", "", "read the records from a zone or JSON `file` (- for stdin)")
+	printChanges := fs.Bool("changes", false, changesUsage)
+	if err := parseArgs(fs, args, 1, -1); err != nil {
+		return err
+	}
+	zone := fs.Arg(0)
+
+	var records []libdns.Record
+	var err error
+	if *file != "" {
+		records, err = e.readRecords(*file, zone)
+	} else {
+		records, err = route53.ParseZoneFile(strings.NewReader(strings.Join(fs.Args()[1:], "\n")), zone, nil)
+	}
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		fs.Usage()
+		return errUsage
+	}
+
+	ctx, changes := recordChanges(ctx, *printChanges)
+
+	affected, err := method(ctx, zone, records)
+	if err != nil {
+		return err
+	}
+	return e.writeResult(affected, changes())
+}
+
+func runExport(ctx context.Context, e *env, args []string) error {
+	fs := newFlagSet(e, "export")
+	if err := parseArgs(fs, args, 1, 1); err != nil {
+		return err
+	}
+	return e.provider.ExportZone(ctx, fs.Arg(0), e.stdout)
+}
+
+func runImport(ctx context.Context, e *env, args []string) error {
+	fs := newFlagSet(e, "import")
+	var opts route53.ImportZoneOptions
+	fs.BoolVar(&opts.SkipApexSOA, "skip-apex-soa", false, "leave the zone's SOA record alone")
+	fs.BoolVar(&opts.SkipApexNS, "skip-apex-ns", false, "leave the zone's apex NS records alone")
+	printChanges := fs.Bool("changes", false, changesUsage)
+	if err := parseArgs(fs, args, 2, 2); err != nil {
+		return err
+	}
+	zone := fs.Arg(0)
+
+	records, err := e.readRecords(fs.Arg(1), zone)
+	if err != nil {
+		return err
+	}
+
+	ctx, changes := recordChanges(ctx, *printChanges)
+
+	imported, err := e.provider.ImportZone(ctx, zone, records, opts)
+	if err != nil {
+		return err
+	}
+	return e.writeResult(imported, changes())
+}
+
+// changesUsage is the usage of the -changes flag of the commands changing
+// records.
+const changesUsage = "print the changes submitted, after reading the current values of the record sets"
+
+// recordChanges attaches a ChangeRecorder to ctx if the changes are printed,
+// and returns the function returning them. Recording makes the provider read
+// the current values of the record sets it replaces, so it is skipped
+// otherwise.
+func recordChanges(ctx context.Context, enabled bool) (context.Context, func() []route53.Change) {
+	if !enabled {
+		return ctx, func() []route53.Change { return nil }
+	}
+	var recorder route53.ChangeRecorder
+	return route53.WithChangeRecorder(ctx, &recorder), recorder.Changes
+}
+
+func runDiff(ctx context.Context, e *env, args []string) error {
+	fs := newFlagSet(e, "diff")
+	skipApex := fs.Bool("skip-apex", false, "ignore the apex SOA and NS records")
+	zones := fs.Bool("zones", false, "compare two hosted zones instead of a zone and a file")
+	if err := parseArgs(fs, args, 2, 2); err != nil {
+		return err
+	}
+	zone := fs.Arg(0)
+
+	var diffs []route53.RecordSetDiff
+	var err error
+	if *zones {
+		diffs, err = e.provider.DiffZones(ctx, zone, nil, fs.Arg(1))
+	} else {
+		diffs, err = e.diffFile(ctx, zone, fs.Arg(1))
+	}
+	if err != nil {
+		return err
+	}
+
+	if *skipApex {
+		diffs = slices.DeleteFunc(diffs, func(d route53.RecordSetDiff) bool {
+			return (d.Name == "@" || d.Name == "") && (d.Type == "SOA" || d.Type == "NS")
+		})
+	}
+
+	if err = e.writeDiff(diffs); err != nil {
+		return err
+	}
+	if len(diffs) > 0 {
+		return errDifferences
+	}
+	return nil
+}
+
+// diffFile compares the records of a zone with those of a file. Alias
+// records are included, as export writes them, so an exported zone compares
+// equal to the zone.
+func (e *env) diffFile(ctx context.Context, zone, file string) ([]route53.RecordSetDiff, error) {
+	desired, err := e.readRecords(file, zone)
+	if err != nil {
+		return nil, err
+	}
+	e.provider.AliasRecords = true
+	current, err := e.provider.GetRecords(ctx, zone)
+	if err != nil {
+		return nil, err
+	}
+	return route53.DiffRecords(current, desired), nil
+}
+
+func runWait(ctx context.Context, e *env, args []string) error {
+	fs := newFlagSet(e, "wait")
+	statusOnly := fs.Bool("status", false, "print the current status without waiting")
+	if err := parseArgs(fs, args, 1, -1); err != nil {
+		return err
+	}
+
+	if !*statusOnly {
+		changes := make([]route53.Change, 0, fs.NArg())
+		for _, id := range fs.Args() {
+			changes = append(changes, route53.Change{ID: id})
+		}
+		if err := e.provider.WaitForChanges(ctx, changes...); err != nil {
+			return err
+		}
+	}
+
+	changes := make([]route53.Change, 0, fs.NArg())
+	for _, id := range fs.Args() {
+		change, err := e.provider.ChangeStatus(ctx, id)
+		if err != nil {
+			return err
+		}
+		changes = append(changes, change)
+	}
+	return e.writeChanges(e.stdout, changes)
+}
+
+// readRecords reads the records of a zone file, or of stdin for "-". Files
+// ending in .json hold a JSON array of route53.SerializedRecord instead.
+// $INCLUDE directives are resolved relative to the file's directory.
+func (e *env) readRecords(file, zone string) ([]libdns.Record, error) {
+	if file == "-" {
+		return route53.ParseZoneFile(e.stdin, zone, nil)
+	}
+
+	if strings.EqualFold(filepath.Ext(file), ".json") {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		return route53.UnmarshalRecords(data)
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return route53.ParseZoneFile(f, zone, os.DirFS(filepath.Dir(file)))
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/libdns/route53"
+)
+
+// Output formats.
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+)
+
+// globalOptions are the flags shared by all commands.
+type globalOptions struct {
+	config       string
+	region       string
+	profile      string
+	hostedZoneID string
+	wait         bool
+	maxWait      time.Duration
+	output       string
+	debug        bool
+}
+
+// register defines the flags on fs.
+func (o *globalOptions) register(fs *flag.FlagSet) {
+	fs.StringVar(&o.config, "config", "", "JSON `file` with route53.Provider fields")
+	fs.StringVar(&o.region, "region", "", "AWS region")
+	fs.StringVar(&o.profile, "profile", "", "AWS profile")
+	fs.StringVar(&o.hostedZoneID, "hosted-zone-id", "", "hosted zone `ID`, instead of looking it up by name")
+	fs.BoolVar(&o.wait, "wait", false, "wait for changes to be INSYNC (sets wait_for_route53_sync)")
+	fs.DurationVar(&o.maxWait, "max-wait", 0, "maximum time to wait for changes (sets route53_max_wait)")
+	fs.StringVar(&o.output, "o", outputTable, "output `format`: table or json")
+	fs.BoolVar(&o.debug, "debug", false, "log provider debug events to stderr")
+}
+
+// provider builds the Provider from the configuration file and the flags.
+func (o *globalOptions) provider(stderr io.Writer) (*route53.Provider, error) {
+	if o.output != outputTable && o.output != outputJSON {
+		return nil, fmt.Errorf("unknown output format %q", o.output)
+	}
+
+	provider := &route53.Provider{}
+	if o.config != "" {
+		data, err := os.ReadFile(o.config)
+		if err != nil {
+			return nil, err
+		}
+		if err = json.Unmarshal(data, provider); err != nil {
+			return nil, fmt.Errorf("reading %s: %w", o.config, err)
+		}
+	}
+
+	if o.region != "" {
+		provider.Region = o.region
+	}
+	if o.profile != "" {
+		provider.Profile = o.profile
+	}
+	if o.hostedZoneID != "" {
+		provider.HostedZoneID = o.hostedZoneID
+	}
+	if o.wait {
+		provider.WaitForRoute53Sync = true
+	}
+	if o.maxWait > 0 {
+		provider.Route53MaxWait = o.maxWait
+	}
+	if o.debug {
+		provider.Logger = slog.New(slog.NewTextHandler(stderr, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	}
+	return provider, nil
+}
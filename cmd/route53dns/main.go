@@ -0,0 +1,113 @@
+// Command route53dns manages Route53 hosted zones and records from the
+// command line, through the same route53.Provider code paths as libdns
+// consumers such as Caddy.
+//
+// Usage:
+//
+//	route53dns [flags] <command> [arguments]
+//
+// The commands are:
+//
+//	zones                     list hosted zones
+//	get <zone>                list the records of a zone
+//	append <zone> <record>... add records
+//	set <zone> <record>...    replace record sets
+//	delete <zone> <record>... delete records
+//	export <zone>             write the zone as a BIND zone file
+//	import <zone> <file>      UPSERT the record sets of a zone file
+//	diff <zone> <file|zone>   compare a zone with a zone file or another zone
+//	wait <change-id>...       wait for changes to be INSYNC
+//
+// Records are given in zone file syntax relative to the zone, for example
+// "www 300 IN A 192.0.2.1", or read with -f from a zone file or from a JSON
+// file holding an array of route53.SerializedRecord. The commands changing
+// records print the changes they submitted with -changes. The provider is
+// configured with -config, a JSON file with the same fields as
+// route53.Provider, and the flags below, which take precedence.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+)
+
+// errUsage reports invalid command-line arguments.
+var errUsage = errors.New("invalid usage")
+
+// errDifferences makes diff exit with status 1, like diff(1), when the zone
+// differs from the file or other zone.
+var errDifferences = errors.New("zones differ")
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	err := run(ctx, os.Args[1:], os.Stdin, os.Stdout, os.Stderr)
+	stop()
+
+	switch {
+	case err == nil:
+	case errors.Is(err, errDifferences):
+		os.Exit(1)
+	case errors.Is(err, errUsage), errors.Is(err, flag.ErrHelp):
+		os.Exit(2)
+	default:
+		fmt.Fprintln(os.Stderr, "route53dns:", err)
+		os.Exit(1)
+	}
+}
+
+// run parses the global flags and runs the command.
+func run(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("route53dns", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() { usage(fs, stderr) }
+
+	var opts globalOptions
+	opts.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errUsage
+	}
+
+	cmd, ok := commands()[fs.Arg(0)]
+	if !ok {
+		fmt.Fprintf(stderr, "route53dns: unknown command %q\n", fs.Arg(0))
+		fs.Usage()
+		return errUsage
+	}
+
+	provider, err := opts.provider(stderr)
+	if err != nil {
+		return err
+	}
+
+	env := &env{
+		provider: provider,
+		output:   opts.output,
+		stdin:    stdin,
+		stdout:   stdout,
+		stderr:   stderr,
+	}
+	return cmd.run(ctx, env, fs.Args()[1:])
+}
+
+// usage prints the command-line help.
+func usage(fs *flag.FlagSet, w io.Writer) {
+	fmt.Fprintln(w, "Usage: route53dns [flags] <command> [arguments]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	for _, name := range commandNames() {
+		cmd := commands()[name]
+		fmt.Fprintf(w, "  %-28s %s\n", name+" "+cmd.args, cmd.summary)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Flags:")
+	fs.PrintDefaults()
+}
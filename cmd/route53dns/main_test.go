@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/libdns/libdns"
+	"github.com/libdns/route53"
+)
+
+func TestFlattenDiff(t *testing.T) {
+	a := libdns.Address{Name: "www", TTL: 300 * time.Second, IP: netip.MustParseAddr("192.0.2.1")}
+	b := libdns.Address{Name: "www", TTL: 300 * time.Second, IP: netip.MustParseAddr("192.0.2.2")}
+	c := libdns.TXT{Name: "WWW", TTL: 300 * time.Second, Text: "hello"}
+	cLower := libdns.TXT{Name: "www", TTL: 300 * time.Second, Text: "hello"}
+	d := libdns.Address{Name: "www", TTL: 60 * time.Second, IP: netip.MustParseAddr("192.0.2.1")}
+
+	removed, added := flattenDiff(route53.DiffRecords([]libdns.Record{a, b, c}, []libdns.Record{d, b, cLower}))
+	if len(removed) != 1 || removed[0] != a {
+		t.Errorf("expected only %v removed, got %v", a, removed)
+	}
+	if len(added) != 1 || added[0] != d {
+		t.Errorf("expected only %v added, got %v", d, added)
+	}
+}
+
+func TestRunUsage(t *testing.T) {
+	cases := []struct {
+		name     string
+		args     []string
+		expected error
+		contains string
+	}{
+		{name: "no command", args: nil, expected: errUsage, contains: "Usage: route53dns"},
+		{name: "unknown command", args: []string{"frobnicate"}, expected: errUsage, contains: `unknown command "frobnicate"`},
+		{name: "missing zone", args: []string{"get"}, expected: errUsage, contains: "Usage: route53dns get"},
+		{name: "missing records", args: []string{"set", "example.com."}, expected: errUsage, contains: "Usage: route53dns set"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			err := run(context.Background(), c.args, strings.NewReader(""), &stdout, &stderr)
+			if !errors.Is(err, c.expected) {
+				t.Errorf("expected %v, got %v", c.expected, err)
+			}
+			if !strings.Contains(stderr.String(), c.contains) {
+				t.Errorf("expected stderr to contain %q, got:\n%s", c.contains, stderr.String())
+			}
+		})
+	}
+}
+
+func TestRunInvalidRecord(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	err := run(context.Background(), []string{"append", "example.com.", "www 300 IN A not-an-ip"},
+		strings.NewReader(""), &stdout, &stderr)
+	if err == nil || !strings.Contains(err.Error(), "failed to parse A record www") {
+		t.Errorf("expected a parse error, got %v", err)
+	}
+}
+
+func TestRecordChanges(t *testing.T) {
+	ctx := context.Background()
+	if recordingCtx, changes := recordChanges(ctx, false); recordingCtx != ctx || changes() != nil {
+		t.Error("expected no recorder without -changes")
+	}
+	if recordingCtx, _ := recordChanges(ctx, true); recordingCtx == ctx {
+		t.Error("expected a recorder with -changes")
+	}
+}
+
+func TestExportDiffAlias(t *testing.T) {
+	listing := `<ListResourceRecordSetsResponse xmlns="https://route53.amazonaws.com/doc/2013-04-01/">` +
+		`<ResourceRecordSets>` +
+		`<ResourceRecordSet><Name>example.com.</Name><Type>A</Type><AliasTarget>` +
+		`<HostedZoneId>Z2FDTNDATAQYW2</HostedZoneId><DNSName>d111111abcdef8.cloudfront.net.</DNSName>` +
+		`<EvaluateTargetHealth>false</EvaluateTargetHealth></AliasTarget></ResourceRecordSet>` +
+		`<ResourceRecordSet><Name>www.example.com.</Name><Type>A</Type><TTL>300</TTL>` +
+		`<ResourceRecords><ResourceRecord><Value>192.0.2.1</Value></ResourceRecord></ResourceRecords>` +
+		`</ResourceRecordSet>` +
+		`</ResourceRecordSets><IsTruncated>false</IsTruncated><MaxItems>300</MaxItems>` +
+		`</ListResourceRecordSetsResponse>`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/xml")
+		io.WriteString(w, listing) //nolint:errcheck // test server
+	}))
+	defer server.Close()
+
+	t.Setenv("AWS_ENDPOINT_URL", server.URL)
+	t.Setenv("AWS_REGION", "us-east-1")
+	t.Setenv("AWS_ACCESS_KEY_ID", "test")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "test")
+	t.Setenv("AWS_CONFIG_FILE", filepath.Join(t.TempDir(), "config"))
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(t.TempDir(), "credentials"))
+
+	var exported, stderr bytes.Buffer
+	err := run(context.Background(), []string{"-hosted-zone-id", "Z1", "export", "example.com."},
+		strings.NewReader(""), &exported, &stderr)
+	if err != nil {
+		t.Fatalf("export: %v\n%s", err, stderr.String())
+	}
+	if !strings.Contains(exported.String(), ";ALIAS") {
+		t.Fatalf("expected the alias exported, got:\n%s", exported.String())
+	}
+
+	var stdout bytes.Buffer
+	err = run(context.Background(), []string{"-hosted-zone-id", "Z1", "diff", "example.com.", "-"},
+		&exported, &stdout, &stderr)
+	if err != nil {
+		t.Errorf("expected no differences, got %v:\n%s", err, stdout.String())
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/libdns/libdns"
+	"github.com/libdns/route53"
+)
+
+// writeJSON writes v as indented JSON.
+func writeJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// writeZones prints hosted zones.
+func (e *env) writeZones(zones []route53.Zone) error {
+	if e.output == outputJSON {
+		if zones == nil {
+			zones = []route53.Zone{}
+		}
+		return writeJSON(e.stdout, zones)
+	}
+
+	tw := tabwriter.NewWriter(e.stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tNAME\tPRIVATE")
+	for _, zone := range zones {
+		fmt.Fprintf(tw, "%s\t%s\t%t\n", zone.ID, zone.Name, zone.Private)
+	}
+	return tw.Flush()
+}
+
+// writeRecords prints records.
+func (e *env) writeRecords(records []libdns.Record) error {
+	if e.output == outputJSON {
+		return writeJSON(e.stdout, route53.Records(records).Serialize())
+	}
+	return writeRecordTable(e.stdout, records, "")
+}
+
+// writeRecordTable prints records as a table, each line starting with
+// prefix.
+func writeRecordTable(w io.Writer, records []libdns.Record, prefix string) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "%sNAME\tTTL\tTYPE\tDATA\n", prefix)
+	for _, record := range records {
+		rr := record.RR()
+		data := rr.Data
+		if rr.Type == "TXT" || rr.Type == "SPF" {
+			data = strconv.Quote(data)
+		}
+		fmt.Fprintf(tw, "%s%s\t%d\t%s\t%s\n", prefix, rr.Name, int64(rr.TTL/time.Second), rr.Type, data)
+	}
+	return tw.Flush()
+}
+
+// writeResult prints the records affected by a command and the changes it
+// submitted. In table form, the changes go to stderr so stdout holds only
+// records.
+func (e *env) writeResult(records []libdns.Record, changes []route53.Change) error {
+	if e.output == outputJSON {
+		if changes == nil {
+			changes = []route53.Change{}
+		}
+		return writeJSON(e.stdout, struct {
+			Records []route53.SerializedRecord `json:"records"`
+			Changes []route53.Change           `json:"changes"`
+		}{route53.Records(records).Serialize(), changes})
+	}
+
+	if err := writeRecordTable(e.stdout, records, ""); err != nil {
+		return err
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+	return e.writeChanges(e.stderr, changes)
+}
+
+// writeChanges prints changes.
+func (e *env) writeChanges(w io.Writer, changes []route53.Change) error {
+	if e.output == outputJSON {
+		return writeJSON(w, changes)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "CHANGE\tSTATUS\tSUBMITTED")
+	for _, change := range changes {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", change.ID, change.Status, change.SubmittedAt.Format(time.RFC3339))
+	}
+	return tw.Flush()
+}
+
+// writeDiff prints the records only in the zone, prefixed with "-", and the
+// records only in the file or other zone, prefixed with "+". Records whose
+// TTL changed appear on both sides.
+func (e *env) writeDiff(diffs []route53.RecordSetDiff) error {
+	if e.output == outputJSON {
+		if diffs == nil {
+			diffs = []route53.RecordSetDiff{}
+		}
+		return writeJSON(e.stdout, diffs)
+	}
+
+	removed, added := flattenDiff(diffs)
+	if len(removed) > 0 {
+		if err := writeRecordTable(e.stdout, removed, "- "); err != nil {
+			return err
+		}
+	}
+	if len(added) > 0 {
+		return writeRecordTable(e.stdout, added, "+ ")
+	}
+	return nil
+}
+
+// flattenDiff returns the records removed and added by diffs.
+func flattenDiff(diffs []route53.RecordSetDiff) ([]libdns.Record, []libdns.Record) {
+	var removed, added []libdns.Record
+	for _, diff := range diffs {
+		removed = append(removed, diff.Removed()...)
+		added = append(added, diff.Added()...)
+	}
+	return removed, added
+}
@@ -0,0 +1,154 @@
+package route53
+
+import (
+	"context"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/route53/types"
+)
+
+// CopyZoneOptions configures CopyZone.
+type CopyZoneOptions struct {
+	// SourceZone is the name of the zone to copy.
+	SourceZone string `json:"source_zone"`
+
+	// DestinationZone is the name of the zone to copy to. It defaults to
+	// SourceZone, for moving a zone to another hosted zone of the same
+	// name, for example in another AWS account.
+	DestinationZone string `json:"destination_zone,omitempty"`
+
+	// Prune deletes the record sets of the destination zone missing from
+	// the source zone, making it an exact copy. By default they are left
+	// alone.
+	Prune bool `json:"prune,omitempty"`
+
+	// SkipHealthChecks leaves out the health checks of the record sets, for
+	// copying to another AWS account, where they do not exist.
+	SkipHealthChecks bool `json:"skip_health_checks,omitempty"`
+}
+
+// CopyZone copies every record set of a zone managed by src to a zone managed
+// by dst, which may use other credentials, such as those of another AWS
+// account, or be src itself. Alias targets, routing policies and TTLs are
+// copied, except for:
+//
+//   - The apex SOA and NS record sets, which belong to each hosted zone.
+//   - Record sets created by traffic policy instances.
+//
+// When the zones have different names, domain names within the source zone
+// are rewritten to the destination zone, both in record data such as CNAME
+// targets and in the targets of aliases. Aliases to record sets of the source
+// hosted zone are pointed at the destination hosted zone.
+//
+// The record sets are UPSERTed in batches like those of Restore, skipping
+// those the destination already holds, under their per-tuple locks on dst,
+// and the submitted changes are returned.
+func CopyZone(ctx context.Context, src, dst *Provider, opts CopyZoneOptions) (_ []Change, err error) {
+	ctx, end := dst.startSpan(ctx, "CopyZone", zoneAttributes(opts.SourceZone, nil))
+	defer end(&err)
+
+	if opts.DestinationZone == "" {
+		opts.DestinationZone = opts.SourceZone
+	}
+
+	snapshot, err := src.Snapshot(ctx, opts.SourceZone)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx = withOperation(ctx, OperationCopy)
+
+	zone := absoluteZoneName(opts.DestinationZone)
+	zoneID, err := dst.getZoneID(ctx, zone)
+	if err != nil {
+		return nil, err
+	}
+
+	copied := copySnapshot(snapshot, zone, strings.TrimPrefix(zoneID, hostedZonePrefix), opts)
+	changes, sets, unlock, err := dst.planLocked(ctx, zoneID,
+		func(current []types.ResourceRecordSet) ([]types.Change, []RecordSetChange, error) {
+			planned, plannedSets, planErr := planRestore(zone, current, copied, false)
+			if planErr != nil || opts.Prune {
+				return planned, plannedSets, planErr
+			}
+			planned, plannedSets = withoutDeletes(planned, plannedSets)
+			return planned, plannedSets, nil
+		})
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	if len(changes) == 0 {
+		return nil, nil
+	}
+
+	dst.Logger.DebugContext(ctx, "copying zone",
+		"source_zone", snapshot.Zone, "zone", zone, "changes", len(changes))
+
+	ctx, waitDeferred := dst.deferSync(ctx)
+
+	submitted, err := dst.submitChanges(ctx, zoneID, zone, changes, sets)
+	unlock()
+	if err != nil {
+		return submitted, err
+	}
+
+	if err = waitDeferred(); err != nil {
+		return submitted, err
+	}
+	return submitted, nil
+}
+
+// copySnapshot rewrites a snapshot for the hosted zone zoneID named zone,
+// leaving out what CopyZone does not copy.
+func copySnapshot(snapshot Snapshot, zone, zoneID string, opts CopyZoneOptions) Snapshot {
+	from := snapshot.Zone
+	sourceID := strings.TrimPrefix(snapshot.HostedZoneID, hostedZonePrefix)
+	rename := !strings.EqualFold(from, zone)
+
+	copied := Snapshot{Zone: zone, HostedZoneID: zoneID, Time: snapshot.Time}
+	for _, set := range snapshot.RecordSets {
+		if set.TrafficPolicyInstanceID != "" || isApexDefault(*set.resourceRecordSet(from), from) {
+			continue
+		}
+
+		if opts.SkipHealthChecks {
+			set.HealthCheckID = ""
+		}
+		if rename {
+			values := make([]string, 0, len(set.Values))
+			for _, value := range set.Values {
+				value, _ = rewriteDataOrigin(set.Type, value, from, zone)
+				values = append(values, value)
+			}
+			set.Values = values
+		}
+		if set.Alias != nil {
+			alias := *set.Alias
+			if rename {
+				alias.Target = rewriteName(alias.Target, from, zone)
+			}
+			if strings.EqualFold(alias.HostedZoneID, sourceID) {
+				alias.HostedZoneID = zoneID
+			}
+			set.Alias = &alias
+		}
+
+		copied.RecordSets = append(copied.RecordSets, set)
+	}
+	return copied
+}
+
+// withoutDeletes leaves out the deletions from changes and the matching
+// record set changes.
+func withoutDeletes(changes []types.Change, sets []RecordSetChange) ([]types.Change, []RecordSetChange) {
+	var keptChanges []types.Change
+	var keptSets []RecordSetChange
+	for i, change := range changes {
+		if change.Action != types.ChangeActionDelete {
+			keptChanges = append(keptChanges, change)
+			keptSets = append(keptSets, sets[i])
+		}
+	}
+	return keptChanges, keptSets
+}
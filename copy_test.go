@@ -0,0 +1,160 @@
+package route53 //nolint:testpackage // Testing internal functions
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/route53/types"
+)
+
+func TestCopySnapshot(t *testing.T) {
+	source := "old.example."
+	sets := []types.ResourceRecordSet{
+		{
+			Name:            aws.String(source),
+			Type:            types.RRTypeSoa,
+			TTL:             aws.Int64(900),
+			ResourceRecords: []types.ResourceRecord{{Value: aws.String("ns-1.awsdns-01.org. hostmaster. 1 7200 900 1209600 86400")}},
+		},
+		{
+			Name:            aws.String(source),
+			Type:            types.RRTypeMx,
+			TTL:             aws.Int64(3600),
+			ResourceRecords: []types.ResourceRecord{{Value: aws.String("10 mx.old.example.")}},
+		},
+		{
+			Name:          aws.String("www." + source),
+			Type:          types.RRTypeA,
+			HealthCheckId: aws.String("hc-1"),
+			SetIdentifier: aws.String("primary"),
+			Failover:      types.ResourceRecordSetFailoverPrimary,
+			AliasTarget: &types.AliasTarget{
+				DNSName:      aws.String("lb.old.example."),
+				HostedZoneId: aws.String("ZSOURCE"),
+			},
+		},
+		{
+			Name: aws.String("cdn." + source),
+			Type: types.RRTypeA,
+			AliasTarget: &types.AliasTarget{
+				DNSName:      aws.String("d111111abcdef8.cloudfront.net."),
+				HostedZoneId: aws.String("Z2FDTNDATAQYW2"),
+			},
+		},
+		{
+			Name:                    aws.String("tp." + source),
+			Type:                    types.RRTypeA,
+			TrafficPolicyInstanceId: aws.String("tpi-1"),
+			AliasTarget:             &types.AliasTarget{DNSName: aws.String("x.old.example."), HostedZoneId: aws.String("ZSOURCE")},
+		},
+	}
+
+	snapshot := Snapshot{Zone: source, HostedZoneID: "ZSOURCE"}
+	for _, set := range sets {
+		snapshot.RecordSets = append(snapshot.RecordSets, snapshotRecordSet(set, source))
+	}
+
+	copied := copySnapshot(snapshot, "new.example.", "ZDEST", CopyZoneOptions{SkipHealthChecks: true})
+	if copied.Zone != "new.example." || copied.HostedZoneID != "ZDEST" {
+		t.Errorf("unexpected destination: %s %s", copied.Zone, copied.HostedZoneID)
+	}
+	if len(copied.RecordSets) != 3 {
+		t.Fatalf("expected the apex SOA and traffic policy record sets left out, got %+v", copied.RecordSets)
+	}
+
+	mx, www, cdn := copied.RecordSets[0], copied.RecordSets[1], copied.RecordSets[2]
+	if len(mx.Values) != 1 || mx.Values[0] != "10 mx.new.example." {
+		t.Errorf("expected the MX target rewritten, got %v", mx.Values)
+	}
+	if www.Alias.Target != "lb.new.example." || www.Alias.HostedZoneID != "ZDEST" {
+		t.Errorf("expected the same-zone alias pointed at the destination, got %+v", www.Alias)
+	}
+	if www.HealthCheckID != "" || www.Failover != "PRIMARY" || www.SetIdentifier != "primary" {
+		t.Errorf("expected the routing policy kept without the health check, got %+v", www)
+	}
+	if cdn.Alias.Target != "d111111abcdef8.cloudfront.net." || cdn.Alias.HostedZoneID != "Z2FDTNDATAQYW2" {
+		t.Errorf("expected the CloudFront alias left alone, got %+v", cdn.Alias)
+	}
+	if snapshot.RecordSets[2].Alias.HostedZoneID != "ZSOURCE" {
+		t.Error("expected the source snapshot left unmodified")
+	}
+
+	// only UPSERTs without Prune
+	current := []types.ResourceRecordSet{{
+		Name:            aws.String("api.new.example."),
+		Type:            types.RRTypeTxt,
+		TTL:             aws.Int64(60),
+		ResourceRecords: []types.ResourceRecord{{Value: aws.String(`"x"`)}},
+	}}
+	changes, recordSets, err := planRestore("new.example.", current, copied, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(changes) != 4 || changes[0].Action != types.ChangeActionDelete {
+		t.Fatalf("expected a deletion and 3 UPSERTs, got %+v", changes)
+	}
+	changes, recordSets = withoutDeletes(changes, recordSets)
+	if len(changes) != 3 || len(recordSets) != 3 || recordSets[0].Type != "MX" {
+		t.Errorf("expected the deletion left out, got %+v", recordSets)
+	}
+}
+
+func TestCopyZone(t *testing.T) {
+	src := &Provider{HostedZoneID: "ZSOURCE"}
+	newCannedProvider(t, src, cannedResponse{status: http.StatusOK, body: listRecordSetsResponse(
+		recordSetXML("old.example.", "SOA", "ns-1.awsdns-01.org. hostmaster. 1 7200 900 1209600 86400"),
+		recordSetXML("old.example.", "MX", "10 mx.old.example."),
+		recordSetXML("api.old.example.", "A", "192.0.2.9"),
+		`<ResourceRecordSet><Name>www.old.example.</Name><Type>A</Type><AliasTarget>`+
+			`<HostedZoneId>ZSOURCE</HostedZoneId><DNSName>lb.old.example.</DNSName>`+
+			`<EvaluateTargetHealth>false</EvaluateTargetHealth></AliasTarget></ResourceRecordSet>`,
+	)})
+
+	dst := &Provider{HostedZoneID: "ZDEST"}
+	canned := newCannedProvider(t, dst,
+		cannedResponse{status: http.StatusOK, body: listRecordSetsResponse(
+			recordSetXML("other.new.example.", "A", "192.0.2.1"),
+		)},
+		cannedResponse{status: http.StatusOK, body: listRecordSetsResponse(
+			recordSetXML("other.new.example.", "A", "192.0.2.1"),
+		)},
+		cannedResponse{status: http.StatusOK, body: changeResourceRecordSetsResponse},
+	)
+
+	changes, err := CopyZone(context.Background(), src, dst, CopyZoneOptions{
+		SourceZone:      "old.example.",
+		DestinationZone: "new.example.",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(changes) != 1 || len(canned.requests) != 3 {
+		t.Fatalf("expected a single change batch after listing twice, got %d changes and %d requests",
+			len(changes), len(canned.requests))
+	}
+
+	request := canned.requests[2]
+	if request.URL.Path != "/2013-04-01/hostedzone/ZDEST/rrset" {
+		t.Errorf("expected the changes sent to the destination, got %s", request.URL.Path)
+	}
+	body := canned.bodies[2]
+	for _, expected := range []string{
+		"<Name>new.example.</Name>",
+		"<Value>10 mx.new.example.</Value>",
+		"<Name>api.new.example.</Name>",
+		"<DNSName>lb.new.example.</DNSName>",
+		"<HostedZoneId>ZDEST</HostedZoneId>",
+	} {
+		if !strings.Contains(body, expected) {
+			t.Errorf("expected the request to contain %s, got:\n%s", expected, body)
+		}
+	}
+	for _, unexpected := range []string{"SOA", "DELETE", "ZSOURCE", "old.example."} {
+		if strings.Contains(body, unexpected) {
+			t.Errorf("expected the request not to contain %s, got:\n%s", unexpected, body)
+		}
+	}
+}
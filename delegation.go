@@ -0,0 +1,263 @@
+package route53
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	r53 "github.com/aws/aws-sdk-go-v2/service/route53"
+	"github.com/aws/aws-sdk-go-v2/service/route53/types"
+	"github.com/libdns/libdns"
+)
+
+// delegationTTL is the TTL of the NS record sets created by DelegateZone,
+// the same as Route53 uses for the apex NS record set of a new zone.
+const delegationTTL = 172800 * time.Second
+
+// DelegationStatus compares the NS record set delegating a child zone in its
+// parent zone with the name servers of the child's hosted zone.
+type DelegationStatus struct {
+	// Parent is the parent zone name.
+	Parent string `json:"parent"`
+
+	// Child is the child zone name.
+	Child string `json:"child"`
+
+	// Expected are the name servers of the child's hosted zone.
+	Expected []string `json:"expected"`
+
+	// Actual are the values of the NS record set for the child in the parent
+	// zone. Empty if the child is not delegated.
+	Actual []string `json:"actual"`
+
+	// Missing are the expected name servers absent from Actual.
+	Missing []string `json:"missing,omitempty"`
+
+	// Extra are the name servers in Actual that are not expected.
+	Extra []string `json:"extra,omitempty"`
+}
+
+// OK reports whether the delegation matches the child's name servers exactly.
+func (s DelegationStatus) OK() bool {
+	return len(s.Actual) > 0 && len(s.Missing) == 0 && len(s.Extra) == 0
+}
+
+// DelegateZone delegates the child zone from its parent zone: it UPSERTs an
+// NS record set for the child in the parent, pointing at the name servers of
+// the child's hosted zone. Both zones must be hosted in Route53 and visible
+// to this provider; the parent is resolved like for the record methods, the
+// child always by name. It returns the NS records that were set.
+func (p *Provider) DelegateZone(ctx context.Context, parent, child string) (_ []libdns.Record, err error) {
+	ctx, end := p.startSpan(ctx, "DelegateZone", delegationAttributes(parent, child))
+	defer end(&err)
+
+	ctx = withOperation(ctx, OperationSet)
+
+	parentID, key, err := p.delegationTarget(ctx, parent, child)
+	if err != nil {
+		return nil, err
+	}
+
+	nameServers, err := p.zoneNameServers(ctx, absoluteZoneName(child))
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]libdns.Record, 0, len(nameServers))
+	for _, ns := range nameServers {
+		records = append(records, libdns.NS{Name: key.name, TTL: delegationTTL, Target: normalizeName(ns)})
+	}
+
+	p.Logger.DebugContext(ctx, "delegating child zone",
+		"parent", parent, "child", child, "name_servers", nameServers)
+
+	if err = p.setRecordSetLocked(ctx, parentID, absoluteZoneName(parent), key, records); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// Undelegate removes the NS record set delegating the child zone from its
+// parent zone. It returns the NS records that were deleted, if any.
+func (p *Provider) Undelegate(ctx context.Context, parent, child string) (_ []libdns.Record, err error) {
+	ctx, end := p.startSpan(ctx, "Undelegate", delegationAttributes(parent, child))
+	defer end(&err)
+
+	ctx = withOperation(ctx, OperationDelete)
+
+	parentID, key, err := p.delegationTarget(ctx, parent, child)
+	if err != nil {
+		return nil, err
+	}
+	parent = absoluteZoneName(parent)
+
+	unlock, err := p.lockSet(ctx, setLockKey{zoneID: parentID, name: key.name, recordType: key.recordType})
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	existing, err := p.getRecordSet(ctx, parentID, parent, key)
+	if err != nil || len(existing) == 0 {
+		return nil, err
+	}
+
+	p.Logger.DebugContext(ctx, "removing child zone delegation", "parent", parent, "child", child)
+
+	if err = p.deleteRecordSet(ctx, parentID, parent, key.name, key.recordType, existing); err != nil {
+		return nil, err
+	}
+	return existing, nil
+}
+
+// CheckDelegation compares the NS record set for the child in the parent
+// zone with the name servers of the child's hosted zone.
+func (p *Provider) CheckDelegation(ctx context.Context, parent, child string) (_ DelegationStatus, err error) {
+	ctx, end := p.startSpan(ctx, "CheckDelegation", delegationAttributes(parent, child))
+	defer end(&err)
+
+	parentID, key, err := p.delegationTarget(ctx, parent, child)
+	if err != nil {
+		return DelegationStatus{}, err
+	}
+	parent = absoluteZoneName(parent)
+
+	existing, err := p.getRecordSet(ctx, parentID, parent, key)
+	if err != nil {
+		return DelegationStatus{}, err
+	}
+
+	return p.delegationStatus(ctx, parent, absoluteZoneName(child), existing)
+}
+
+// CheckDelegations checks every delegation in the parent zone whose child
+// zone is hosted in Route53 and visible to this provider, and returns the
+// status of each. Delegations to zones hosted elsewhere are skipped.
+func (p *Provider) CheckDelegations(ctx context.Context, parent string) (_ []DelegationStatus, err error) {
+	ctx, end := p.startSpan(ctx, "CheckDelegations", zoneAttributes(parent, nil))
+	defer end(&err)
+
+	parent = absoluteZoneName(parent)
+	parentID, err := p.getZoneID(ctx, parent)
+	if err != nil {
+		return nil, err
+	}
+
+	records, err := p.getRecords(ctx, parentID, parent)
+	if err != nil {
+		return nil, err
+	}
+
+	// group the non-apex NS records by child name, keeping the zone order
+	var children []string
+	delegations := make(map[string][]libdns.Record)
+	for _, record := range records {
+		rr := record.RR()
+		if rr.Type != "NS" || rr.Name == "@" {
+			continue
+		}
+		if _, seen := delegations[rr.Name]; !seen {
+			children = append(children, rr.Name)
+		}
+		delegations[rr.Name] = append(delegations[rr.Name], record)
+	}
+
+	statuses := make([]DelegationStatus, 0, len(children))
+	for _, name := range children {
+		status, statusErr := p.delegationStatus(ctx, parent, libdns.AbsoluteName(name, parent), delegations[name])
+		if errors.Is(statusErr, ErrHostedZoneNotFound) {
+			p.Logger.DebugContext(ctx, "skipping delegation to zone not hosted in Route53",
+				"parent", parent, "child", name)
+			continue
+		}
+		if statusErr != nil {
+			return nil, statusErr
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// delegationStatus builds the DelegationStatus of child from the NS records
+// currently delegating it.
+func (p *Provider) delegationStatus(
+	ctx context.Context,
+	parent, child string,
+	delegation []libdns.Record,
+) (DelegationStatus, error) {
+	nameServers, err := p.zoneNameServers(ctx, child)
+	if err != nil {
+		return DelegationStatus{}, err
+	}
+
+	status := DelegationStatus{Parent: parent, Child: child}
+	for _, ns := range nameServers {
+		status.Expected = append(status.Expected, normalizeName(ns))
+	}
+	for _, record := range delegation {
+		status.Actual = append(status.Actual, normalizeName(record.RR().Data))
+	}
+	return status.compare(), nil
+}
+
+// compare returns s with Expected and Actual sorted and Missing and Extra
+// filled in.
+func (s DelegationStatus) compare() DelegationStatus {
+	slices.Sort(s.Expected)
+	slices.Sort(s.Actual)
+
+	s.Missing, s.Extra = nil, nil
+	for _, ns := range s.Expected {
+		if !slices.Contains(s.Actual, ns) {
+			s.Missing = append(s.Missing, ns)
+		}
+	}
+	for _, ns := range s.Actual {
+		if !slices.Contains(s.Expected, ns) {
+			s.Extra = append(s.Extra, ns)
+		}
+	}
+	return s
+}
+
+// delegationTarget resolves the parent zone and the key of the child's NS
+// record set within it.
+func (p *Provider) delegationTarget(ctx context.Context, parent, child string) (string, recordSetKey, error) {
+	parent = absoluteZoneName(parent)
+	child = absoluteZoneName(child)
+	if !strings.HasSuffix(strings.ToLower(child), "."+strings.ToLower(parent)) {
+		return "", recordSetKey{}, fmt.Errorf("%s is not a subdomain of %s", child, parent)
+	}
+
+	parentID, err := p.getZoneID(ctx, parent)
+	if err != nil {
+		return "", recordSetKey{}, err
+	}
+	return parentID, recordSetKey{name: libdns.RelativeName(child, parent), recordType: "NS"}, nil
+}
+
+// zoneNameServers returns the name servers Route53 assigned to the hosted
+// zone with the given name.
+func (p *Provider) zoneNameServers(ctx context.Context, zone string) ([]string, error) {
+	zoneID, err := p.lookupZoneID(ctx, zone)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := p.client.GetHostedZone(ctx, &r53.GetHostedZoneInput{Id: aws.String(zoneID)})
+	if err != nil {
+		var nshze *types.NoSuchHostedZone
+		if errors.As(err, &nshze) {
+			return nil, fmt.Errorf("NoSuchHostedZone: %w", err)
+		}
+		return nil, err
+	}
+	if out.DelegationSet == nil || len(out.DelegationSet.NameServers) == 0 {
+		return nil, fmt.Errorf("hosted zone %s has no name servers; private zones cannot be delegated", zone)
+	}
+	return out.DelegationSet.NameServers, nil
+}
@@ -0,0 +1,229 @@
+package route53 //nolint:testpackage // Testing internal functions
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// hostedZonesByNameResponse lists hosted zones, given as alternating IDs and
+// names.
+func hostedZonesByNameResponse(idsAndNames ...string) string {
+	zones := ""
+	for i := 0; i+1 < len(idsAndNames); i += 2 {
+		zones += "<HostedZone><Id>/hostedzone/" + idsAndNames[i] + "</Id><Name>" + idsAndNames[i+1] + "</Name>" +
+			"<CallerReference>ref</CallerReference></HostedZone>"
+	}
+	return `<ListHostedZonesByNameResponse xmlns="https://route53.amazonaws.com/doc/2013-04-01/">` +
+		"<HostedZones>" + zones + "</HostedZones><IsTruncated>false</IsTruncated><MaxItems>1</MaxItems>" +
+		"</ListHostedZonesByNameResponse>"
+}
+
+// getHostedZoneResponse describes a hosted zone with the given name servers,
+// or a private one without any.
+func getHostedZoneResponse(id, name string, nameServers ...string) string {
+	delegationSet := ""
+	if len(nameServers) > 0 {
+		delegationSet = "<DelegationSet><NameServers><NameServer>" +
+			strings.Join(nameServers, "</NameServer><NameServer>") +
+			"</NameServer></NameServers></DelegationSet>"
+	}
+	return `<GetHostedZoneResponse xmlns="https://route53.amazonaws.com/doc/2013-04-01/">` +
+		"<HostedZone><Id>/hostedzone/" + id + "</Id><Name>" + name + "</Name>" +
+		"<CallerReference>ref</CallerReference></HostedZone>" + delegationSet + "</GetHostedZoneResponse>"
+}
+
+func TestDelegationStatus(t *testing.T) {
+	expected := []string{"ns-2.awsdns-02.net.", "ns-1.awsdns-01.org."}
+	cases := []struct {
+		name    string
+		actual  []string
+		missing []string
+		extra   []string
+		ok      bool
+	}{
+		{
+			name:   "matching",
+			actual: []string{"ns-1.awsdns-01.org.", "ns-2.awsdns-02.net."},
+			ok:     true,
+		},
+		{
+			name:    "not delegated",
+			missing: []string{"ns-1.awsdns-01.org.", "ns-2.awsdns-02.net."},
+		},
+		{
+			name:    "stale name server",
+			actual:  []string{"ns-1.awsdns-01.org.", "ns-9.awsdns-09.com."},
+			missing: []string{"ns-2.awsdns-02.net."},
+			extra:   []string{"ns-9.awsdns-09.com."},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			status := DelegationStatus{Expected: slices.Clone(expected), Actual: c.actual}.compare()
+			if !slices.Equal(status.Missing, c.missing) {
+				t.Errorf("expected missing %v, got %v", c.missing, status.Missing)
+			}
+			if !slices.Equal(status.Extra, c.extra) {
+				t.Errorf("expected extra %v, got %v", c.extra, status.Extra)
+			}
+			if status.OK() != c.ok {
+				t.Errorf("expected OK() %v, got %v", c.ok, status.OK())
+			}
+		})
+	}
+}
+
+func TestDelegateZone(t *testing.T) {
+	provider := &Provider{HostedZoneID: "ZPARENT"}
+	canned := newCannedProvider(t, provider,
+		cannedResponse{status: http.StatusOK, body: hostedZonesByNameResponse("ZCHILD", "sub.example.com.")},
+		cannedResponse{status: http.StatusOK, body: getHostedZoneResponse("ZCHILD", "sub.example.com.",
+			"ns-1.awsdns-01.org", "ns-2.awsdns-02.net")},
+		cannedResponse{status: http.StatusOK, body: changeResourceRecordSetsResponse},
+	)
+
+	records, err := provider.DelegateZone(context.Background(), "example.com", "sub.example.com")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(records) != 2 || records[0].RR().Name != "sub" || records[0].RR().Data != "ns-1.awsdns-01.org." {
+		t.Errorf("expected the child's name servers, got %v", records)
+	}
+
+	if len(canned.requests) != 3 || canned.requests[2].URL.Path != "/2013-04-01/hostedzone/ZPARENT/rrset" {
+		t.Fatalf("expected the NS record set sent to the parent, got %d requests", len(canned.requests))
+	}
+	for _, expected := range []string{
+		"<Action>UPSERT</Action>",
+		"<Name>sub.example.com.</Name>",
+		"<Type>NS</Type>",
+		"<TTL>172800</TTL>",
+		"<Value>ns-1.awsdns-01.org.</Value>",
+		"<Value>ns-2.awsdns-02.net.</Value>",
+	} {
+		if !strings.Contains(canned.bodies[2], expected) {
+			t.Errorf("expected the request to contain %s, got:\n%s", expected, canned.bodies[2])
+		}
+	}
+}
+
+func TestDelegateZoneErrors(t *testing.T) {
+	cases := []struct {
+		name      string
+		child     string
+		responses []cannedResponse
+		expected  string
+	}{
+		{
+			name:     "not a subdomain",
+			child:    "example.org",
+			expected: "not a subdomain",
+		},
+		{
+			name:  "child not hosted",
+			child: "sub.example.com",
+			responses: []cannedResponse{
+				{status: http.StatusOK, body: hostedZonesByNameResponse()},
+			},
+			expected: ErrHostedZoneNotFound.Error(),
+		},
+		{
+			name:  "private child",
+			child: "sub.example.com",
+			responses: []cannedResponse{
+				{status: http.StatusOK, body: hostedZonesByNameResponse("ZCHILD", "sub.example.com.")},
+				{status: http.StatusOK, body: getHostedZoneResponse("ZCHILD", "sub.example.com.")},
+			},
+			expected: "private zones cannot be delegated",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			provider := &Provider{HostedZoneID: "ZPARENT"}
+			canned := newCannedProvider(t, provider, c.responses...)
+
+			_, err := provider.DelegateZone(context.Background(), "example.com", c.child)
+			if err == nil || !strings.Contains(err.Error(), c.expected) {
+				t.Errorf("expected an error containing %q, got %v", c.expected, err)
+			}
+			if len(canned.requests) != len(c.responses) {
+				t.Errorf("expected nothing submitted, got %d requests", len(canned.requests))
+			}
+		})
+	}
+}
+
+func TestUndelegate(t *testing.T) {
+	provider := &Provider{HostedZoneID: "ZPARENT"}
+	canned := newCannedProvider(t, provider,
+		cannedResponse{status: http.StatusOK, body: listRecordSetsResponse(
+			recordSetXML("example.com.", "NS", "ns-9.awsdns-09.com."),
+			recordSetXML("sub.example.com.", "NS", "ns-1.awsdns-01.org.", "ns-2.awsdns-02.net."),
+		)},
+		cannedResponse{status: http.StatusOK, body: changeResourceRecordSetsResponse},
+		cannedResponse{status: http.StatusOK, body: listRecordSetsResponse(
+			recordSetXML("example.com.", "NS", "ns-9.awsdns-09.com."),
+		)},
+	)
+
+	records, err := provider.Undelegate(context.Background(), "example.com.", "sub.example.com.")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(records) != 2 {
+		t.Errorf("expected the 2 NS records deleted, got %v", records)
+	}
+	body := canned.bodies[1]
+	if !strings.Contains(body, "<Action>DELETE</Action>") || !strings.Contains(body, "<Name>sub.example.com.</Name>") ||
+		strings.Contains(body, "ns-9.awsdns-09.com.") {
+		t.Errorf("expected the child's NS record set deleted, got:\n%s", body)
+	}
+
+	// a child that is no longer delegated is left alone
+	if records, err = provider.Undelegate(context.Background(), "example.com.", "sub.example.com."); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(records) != 0 || len(canned.requests) != 3 {
+		t.Errorf("expected nothing deleted, got %v after %d requests", records, len(canned.requests))
+	}
+}
+
+func TestCheckDelegations(t *testing.T) {
+	provider := &Provider{HostedZoneID: "ZPARENT"}
+	newCannedProvider(t, provider,
+		cannedResponse{status: http.StatusOK, body: listRecordSetsResponse(
+			recordSetXML("example.com.", "NS", "ns-9.awsdns-09.com."),
+			recordSetXML("elsewhere.example.com.", "NS", "ns1.example.net."),
+			recordSetXML("sub.example.com.", "NS", "ns-1.awsdns-01.org.", "ns-3.awsdns-03.co.uk."),
+		)},
+		cannedResponse{status: http.StatusOK, body: hostedZonesByNameResponse()},
+		cannedResponse{status: http.StatusOK, body: hostedZonesByNameResponse("ZCHILD", "sub.example.com.")},
+		cannedResponse{status: http.StatusOK, body: getHostedZoneResponse("ZCHILD", "sub.example.com.",
+			"ns-1.awsdns-01.org", "ns-2.awsdns-02.net")},
+	)
+
+	statuses, err := provider.CheckDelegations(context.Background(), "example.com.")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(statuses) != 1 || statuses[0].Child != "sub.example.com." {
+		t.Fatalf("expected only the delegation to the hosted child checked, got %+v", statuses)
+	}
+	status := statuses[0]
+	if status.OK() || !slices.Equal(status.Missing, []string{"ns-2.awsdns-02.net."}) ||
+		!slices.Equal(status.Extra, []string{"ns-3.awsdns-03.co.uk."}) {
+		t.Errorf("expected the stale name server reported, got %+v", status)
+	}
+
+	// an unreadable parent zone fails
+	provider = &Provider{HostedZoneID: "ZPARENT"}
+	newCannedProvider(t, provider, cannedResponse{status: http.StatusBadRequest, body: invalidChangeBatchResponse})
+	if _, err = provider.CheckDelegations(context.Background(), "example.com."); err == nil {
+		t.Error("expected an error listing the parent zone")
+	}
+}
@@ -0,0 +1,315 @@
+package route53
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+// DiffKind tells how a record set differs between two zones.
+type DiffKind string
+
+// Kinds of record set differences.
+const (
+	// DiffAdded is a record set only in the second zone.
+	DiffAdded DiffKind = "added"
+	// DiffRemoved is a record set only in the first zone.
+	DiffRemoved DiffKind = "removed"
+	// DiffChanged is a record set in both zones, with other values or TTL.
+	DiffChanged DiffKind = "changed"
+)
+
+// RecordSetDiff describes a record set that differs between two zones.
+type RecordSetDiff struct {
+	// Name is the record set name, relative to the zones.
+	Name string `json:"name"`
+
+	// Type is the record set type.
+	Type string `json:"type"`
+
+	// SetIdentifier tells apart the record sets of a routing policy that
+	// share a name and type. It is only known when comparing snapshots.
+	SetIdentifier string `json:"set_identifier,omitempty"`
+
+	// Kind tells whether the record set was added, removed or changed.
+	Kind DiffKind `json:"kind"`
+
+	// From are the records of the set in the first zone; empty if added.
+	From Records `json:"from"`
+
+	// To are the records of the set in the second zone; empty if removed.
+	To Records `json:"to"`
+}
+
+// Removed returns the records of From missing from To, comparing data and
+// TTL. Records whose TTL changed are both removed and added.
+func (d RecordSetDiff) Removed() []libdns.Record {
+	return missingRecords(d.From, d.To)
+}
+
+// Added returns the records of To missing from From, comparing data and TTL.
+func (d RecordSetDiff) Added() []libdns.Record {
+	return missingRecords(d.To, d.From)
+}
+
+// TTLChanged reports whether the record set is in both zones with another
+// TTL.
+func (d RecordSetDiff) TTLChanged() bool {
+	return len(d.From) > 0 && len(d.To) > 0 && d.From[0].RR().TTL != d.To[0].RR().TTL
+}
+
+// DiffRecords compares two lists of records, such as the records of a zone
+// and those of its zone file, by record set. Names are compared without
+// regard to case. Record sets with the same records in any order are equal;
+// the others are returned sorted by name and type.
+//
+// The records must be relative to the same zone; use RewriteOrigin first to
+// compare the records of zones with different names.
+func DiffRecords(from, to []libdns.Record) []RecordSetDiff {
+	return diffGroups(groupForDiff(from), groupForDiff(to))
+}
+
+// DiffSnapshots compares two snapshots by record set, telling apart the
+// record sets of routing policies by their set identifier. When the
+// snapshots are of zones with different names, the domain names within the
+// zone of the first are rewritten to the zone of the second, so that a
+// staging zone can be compared with its production counterpart. The apex SOA
+// and NS record sets of snapshots of different hosted zones always differ and
+// are ignored.
+func DiffSnapshots(from, to Snapshot) ([]RecordSetDiff, error) {
+	skipApex := !strings.EqualFold(
+		strings.TrimPrefix(from.HostedZoneID, hostedZonePrefix),
+		strings.TrimPrefix(to.HostedZoneID, hostedZonePrefix),
+	)
+	fromGroups, err := groupSnapshotForDiff(from, to.Zone, skipApex)
+	if err != nil {
+		return nil, err
+	}
+	toGroups, err := groupSnapshotForDiff(to, to.Zone, skipApex)
+	if err != nil {
+		return nil, err
+	}
+	return diffGroups(fromGroups, toGroups), nil
+}
+
+// DiffZones compares a zone with another zone, which may be managed by
+// another provider, such as one for another AWS account. A nil other
+// compares two zones of p. See DiffSnapshots.
+func (p *Provider) DiffZones(
+	ctx context.Context,
+	zone string,
+	other *Provider,
+	otherZone string,
+) (_ []RecordSetDiff, err error) {
+	ctx, end := p.startSpan(ctx, "DiffZones", zoneAttributes(zone, nil))
+	defer end(&err)
+
+	if other == nil {
+		other = p
+	}
+
+	from, err := p.Snapshot(ctx, zone)
+	if err != nil {
+		return nil, err
+	}
+	to, err := other.Snapshot(ctx, otherZone)
+	if err != nil {
+		return nil, err
+	}
+	return DiffSnapshots(from, to)
+}
+
+// RewriteOrigin rewrites records of the zone from for the zone to: the
+// domain names within from found in the data of CNAME, NS, MX, SRV, HTTPS,
+// SVCB, PTR, DNAME and SOA records, and the targets of aliases, are moved to
+// to. Record names are relative and left as they are. Alias hosted zone IDs
+// are not rewritten.
+func RewriteOrigin(records []libdns.Record, from, to string) ([]libdns.Record, error) {
+	from, to = absoluteZoneName(from), absoluteZoneName(to)
+
+	rewritten := make([]libdns.Record, 0, len(records))
+	for _, record := range records {
+		moved, err := rewriteRecordOrigin(record, from, to)
+		if err != nil {
+			return nil, err
+		}
+		rewritten = append(rewritten, moved)
+	}
+	return rewritten, nil
+}
+
+// rewriteRecordOrigin rewrites a single record for RewriteOrigin.
+func rewriteRecordOrigin(record libdns.Record, from, to string) (libdns.Record, error) {
+	if alias, ok := asAlias(record); ok {
+		alias.Target = rewriteName(alias.Target, from, to)
+		return alias, nil
+	}
+
+	rr := record.RR()
+	data, changed := rewriteDataOrigin(rr.Type, rr.Data, from, to)
+	if !changed {
+		return record, nil
+	}
+
+	rr.Data = data
+	parsed, err := rr.Parse()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse rewritten %s record %s: %w", rr.Type, rr.Name, err)
+	}
+	return parsed, nil
+}
+
+// rewriteDataOrigin moves the domain names within from found in the data of
+// a record of the given type to to, and reports whether it changed any.
+func rewriteDataOrigin(recordType, data, from, to string) (string, bool) {
+	positions := domainNameFields(recordType)
+	if len(positions) == 0 {
+		return data, false
+	}
+
+	fields := strings.SplitN(data, " ", slices.Max(positions)+2)
+	changed := false
+	for _, i := range positions {
+		if i < len(fields) {
+			name := rewriteName(fields[i], from, to)
+			changed = changed || name != fields[i]
+			fields[i] = name
+		}
+	}
+	return strings.Join(fields, " "), changed
+}
+
+// rewriteName moves an absolute name within the zone from to the zone to.
+// Other names are returned as they are.
+func rewriteName(name, from, to string) string {
+	switch lower := strings.ToLower(name); {
+	case lower == strings.ToLower(from):
+		return to
+	case strings.HasSuffix(lower, "."+strings.ToLower(from)):
+		return name[:len(name)-len(from)] + to
+	default:
+		return name
+	}
+}
+
+// diffGroup is a record set being compared.
+type diffGroup struct {
+	key     snapshotKey
+	name    string
+	records []libdns.Record
+}
+
+// groupForDiff groups records by lowercased name and type.
+func groupForDiff(records []libdns.Record) map[snapshotKey]*diffGroup {
+	groups := make(map[snapshotKey]*diffGroup)
+	for _, record := range records {
+		rr := record.RR()
+		key := snapshotKey{name: strings.ToLower(rr.Name), recordType: strings.ToUpper(rr.Type)}
+		group, ok := groups[key]
+		if !ok {
+			group = &diffGroup{key: key, name: rr.Name}
+			groups[key] = group
+		}
+		group.records = append(group.records, record)
+	}
+	return groups
+}
+
+// groupSnapshotForDiff parses the record sets of a snapshot for comparison,
+// rewriting them for the given zone and leaving out the apex SOA and NS
+// record sets if skipApex is set.
+func groupSnapshotForDiff(snapshot Snapshot, zone string, skipApex bool) (map[snapshotKey]*diffGroup, error) {
+	groups := make(map[snapshotKey]*diffGroup, len(snapshot.RecordSets))
+	for _, set := range snapshot.RecordSets {
+		rrset := set.resourceRecordSet(snapshot.Zone)
+		if skipApex && isApexDefault(*rrset, snapshot.Zone) {
+			continue
+		}
+		records, err := parseRecordSet(*rrset, snapshot.Zone)
+		if err != nil {
+			return nil, err
+		}
+		if !strings.EqualFold(snapshot.Zone, absoluteZoneName(zone)) {
+			if records, err = RewriteOrigin(records, snapshot.Zone, zone); err != nil {
+				return nil, err
+			}
+		}
+		groups[set.key()] = &diffGroup{key: set.key(), name: set.Name, records: records}
+	}
+	return groups, nil
+}
+
+// diffGroups compares two groupings of record sets.
+func diffGroups(from, to map[snapshotKey]*diffGroup) []RecordSetDiff {
+	var diffs []RecordSetDiff
+	for key, f := range from {
+		t, ok := to[key]
+		switch {
+		case !ok:
+			diffs = append(diffs, newRecordSetDiff(f, DiffRemoved))
+		case len(missingRecords(f.records, t.records)) > 0 || len(missingRecords(t.records, f.records)) > 0:
+			diff := newRecordSetDiff(f, DiffChanged)
+			diff.To = t.records
+			diffs = append(diffs, diff)
+		}
+	}
+	for key, t := range to {
+		if _, ok := from[key]; !ok {
+			diff := newRecordSetDiff(t, DiffAdded)
+			diff.From, diff.To = nil, t.records
+			diffs = append(diffs, diff)
+		}
+	}
+
+	slices.SortFunc(diffs, func(a, b RecordSetDiff) int {
+		return cmp.Or(
+			compareNames(a.Name, b.Name),
+			cmp.Compare(typeRank(a.Type), typeRank(b.Type)),
+			strings.Compare(a.SetIdentifier, b.SetIdentifier),
+		)
+	})
+	return diffs
+}
+
+// newRecordSetDiff returns a diff of the given kind for a record set, with
+// its records as From.
+func newRecordSetDiff(group *diffGroup, kind DiffKind) RecordSetDiff {
+	return RecordSetDiff{
+		Name:          group.name,
+		Type:          group.key.recordType,
+		SetIdentifier: group.key.setIdentifier,
+		Kind:          kind,
+		From:          group.records,
+	}
+}
+
+// missingRecords returns the records of a missing from b, comparing data and
+// TTL and counting duplicates.
+func missingRecords(a, b []libdns.Record) []libdns.Record {
+	type value struct {
+		data string
+		ttl  time.Duration
+	}
+	remaining := make(map[value]int, len(b))
+	for _, record := range b {
+		rr := record.RR()
+		remaining[value{rr.Data, rr.TTL}]++
+	}
+
+	var missing []libdns.Record
+	for _, record := range a {
+		rr := record.RR()
+		v := value{rr.Data, rr.TTL}
+		if remaining[v] > 0 {
+			remaining[v]--
+			continue
+		}
+		missing = append(missing, record)
+	}
+	return missing
+}
@@ -0,0 +1,123 @@
+package route53 //nolint:testpackage // Testing internal functions
+
+import (
+	"net/netip"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/route53/types"
+	"github.com/libdns/libdns"
+)
+
+func TestDiffRecords(t *testing.T) {
+	www1 := libdns.Address{Name: "www", TTL: 300 * time.Second, IP: netip.MustParseAddr("192.0.2.1")}
+	www2 := libdns.Address{Name: "www", TTL: 300 * time.Second, IP: netip.MustParseAddr("192.0.2.2")}
+	mx := libdns.MX{Name: "@", TTL: time.Hour, Preference: 10, Target: "mx.example.com."}
+	mxShort := libdns.MX{Name: "@", TTL: time.Minute, Preference: 10, Target: "mx.example.com."}
+	txt := libdns.TXT{Name: "_acme-challenge", TTL: time.Minute, Text: "token"}
+	blog := libdns.CNAME{Name: "BLOG", TTL: time.Hour, Target: "example.com."}
+	blogLower := libdns.CNAME{Name: "blog", TTL: time.Hour, Target: "example.com."}
+
+	diffs := DiffRecords(
+		[]libdns.Record{www1, www2, mx, txt, blog},
+		[]libdns.Record{www2, mxShort, blogLower},
+	)
+
+	if len(diffs) != 3 {
+		t.Fatalf("expected 3 diffs, got %+v", diffs)
+	}
+
+	// sorted by name then type: the apex first
+	if d := diffs[0]; d.Name != "@" || d.Kind != DiffChanged || !d.TTLChanged() {
+		t.Errorf("expected the MX TTL changed, got %+v", d)
+	}
+	if d := diffs[1]; d.Name != "_acme-challenge" || d.Kind != DiffRemoved || len(d.To) != 0 {
+		t.Errorf("expected the TXT removed, got %+v", d)
+	}
+	if d := diffs[2]; d.Name != "www" || d.Kind != DiffChanged || d.TTLChanged() {
+		t.Errorf("expected www changed, got %+v", d)
+	}
+	if removed := diffs[2].Removed(); len(removed) != 1 || removed[0] != www1 {
+		t.Errorf("expected only %v removed, got %v", www1, removed)
+	}
+	if added := diffs[2].Added(); len(added) != 0 {
+		t.Errorf("expected nothing added, got %v", added)
+	}
+
+	if diffs = DiffRecords([]libdns.Record{www1, www2}, []libdns.Record{www2, www1}); len(diffs) != 0 {
+		t.Errorf("expected no differences, got %+v", diffs)
+	}
+}
+
+func TestRewriteOrigin(t *testing.T) {
+	records := []libdns.Record{
+		libdns.CNAME{Name: "www", TTL: time.Hour, Target: "web.Staging.example.com."},
+		libdns.CNAME{Name: "ext", TTL: time.Hour, Target: "example.net."},
+		libdns.MX{Name: "@", TTL: time.Hour, Preference: 10, Target: "staging.example.com."},
+		libdns.SRV{Service: "sip", Transport: "tcp", Name: "@", TTL: time.Hour, Port: 5060, Target: "sip.staging.example.com."},
+		libdns.TXT{Name: "@", TTL: time.Hour, Text: "sip.staging.example.com."},
+		Alias{Name: "api", Type: "A", Target: "lb.staging.example.com.", HostedZoneID: "Z1"},
+	}
+
+	rewritten, err := RewriteOrigin(records, "staging.example.com", "example.com.")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []libdns.Record{
+		libdns.CNAME{Name: "www", TTL: time.Hour, Target: "web.example.com."},
+		records[1],
+		libdns.MX{Name: "@", TTL: time.Hour, Preference: 10, Target: "example.com."},
+		libdns.SRV{Service: "sip", Transport: "tcp", Name: "@", TTL: time.Hour, Port: 5060, Target: "sip.example.com."},
+		records[4],
+		Alias{Name: "api", Type: "A", Target: "lb.example.com.", HostedZoneID: "Z1"},
+	}
+	if !reflect.DeepEqual(rewritten, expected) {
+		t.Errorf("expected %#v\ngot      %#v", expected, rewritten)
+	}
+}
+
+func TestDiffSnapshots(t *testing.T) {
+	weighted := func(zone, id string, weight int64, target string) SnapshotRecordSet {
+		return snapshotRecordSet(types.ResourceRecordSet{
+			Name:            aws.String("www." + zone),
+			Type:            types.RRTypeCname,
+			TTL:             aws.Int64(60),
+			SetIdentifier:   aws.String(id),
+			Weight:          aws.Int64(weight),
+			ResourceRecords: []types.ResourceRecord{{Value: aws.String(target)}},
+		}, zone)
+	}
+	apexNS := func(zone, ns string) SnapshotRecordSet {
+		return snapshotRecordSet(types.ResourceRecordSet{
+			Name:            aws.String(zone),
+			Type:            types.RRTypeNs,
+			TTL:             aws.Int64(172800),
+			ResourceRecords: []types.ResourceRecord{{Value: aws.String(ns)}},
+		}, zone)
+	}
+
+	staging := Snapshot{Zone: "staging.example.com.", HostedZoneID: "Z1", RecordSets: []SnapshotRecordSet{
+		apexNS("staging.example.com.", "ns-1.awsdns-01.org."),
+		weighted("staging.example.com.", "blue", 90, "blue.staging.example.com."),
+		weighted("staging.example.com.", "green", 10, "green.staging.example.com."),
+	}}
+	production := Snapshot{Zone: "example.com.", HostedZoneID: "Z2", RecordSets: []SnapshotRecordSet{
+		apexNS("example.com.", "ns-2.awsdns-02.net."),
+		weighted("example.com.", "blue", 90, "blue.example.com."),
+		weighted("example.com.", "green", 10, "green-v2.example.com."),
+	}}
+
+	diffs, err := DiffSnapshots(staging, production)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(diffs) != 1 {
+		t.Fatalf("expected only the green record set to differ, got %+v", diffs)
+	}
+	if d := diffs[0]; d.Name != "www" || d.SetIdentifier != "green" || d.Kind != DiffChanged {
+		t.Errorf("unexpected diff %+v", d)
+	}
+}
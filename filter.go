@@ -0,0 +1,297 @@
+package route53
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	r53 "github.com/aws/aws-sdk-go-v2/service/route53"
+	"github.com/aws/aws-sdk-go-v2/service/route53/types"
+	"github.com/libdns/libdns"
+)
+
+// NameMatch is how RecordFilter.Name is matched against record names.
+type NameMatch string
+
+const (
+	// NameExact matches the records named Name.
+	NameExact NameMatch = "exact"
+	// NameSuffix matches the records named Name and those below it, such as
+	// "_acme-challenge" and "a._acme-challenge" for "_acme-challenge".
+	NameSuffix NameMatch = "suffix"
+	// NameWildcard matches the records whose name matches the Name pattern,
+	// in the syntax of path.Match, where * also matches dots: "*.dev"
+	// matches "a.dev" and "a.b.dev".
+	NameWildcard NameMatch = "wildcard"
+)
+
+// RecordFilter selects the records returned by GetRecordsFiltered. Its zero
+// value selects every record.
+type RecordFilter struct {
+	// Name is the record name, relative to the zone or absolute, or the
+	// pattern to match, as set by NameMatch. "@" is the apex. If empty, every
+	// name matches.
+	Name string
+
+	// NameMatch is how Name is matched. Default is NameExact.
+	NameMatch NameMatch
+
+	// Types are the record types to match, such as "TXT". If empty, every
+	// type matches.
+	Types []string
+
+	// SetIdentifier, if set, only matches the record sets of a routing
+	// policy with this set identifier.
+	SetIdentifier string
+}
+
+// GetRecordsFiltered lists the records of the zone selected by filter,
+// sorted like those of GetRecords.
+//
+// Route53 lists record sets sorted by name with the labels reversed, so for
+// an exact name or a name suffix, and for a wildcard pattern ending with
+// whole labels such as "*.dev", only the matching part of the zone is
+// listed instead of the whole zone.
+func (p *Provider) GetRecordsFiltered(
+	ctx context.Context,
+	zone string,
+	filter RecordFilter,
+) (_ []libdns.Record, err error) {
+	ctx, end := p.startSpan(ctx, "GetRecordsFiltered", zoneAttributes(zone, nil))
+	defer end(&err)
+	ctx = p.withRecordCache(ctx)
+
+	var records []libdns.Record
+	err = p.scanRecords(ctx, zone, filter, func(record libdns.Record) bool {
+		records = append(records, record)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortStableFunc(records, compareZoneFileRecords)
+	return records, nil
+}
+
+// StreamRecords returns an iterator over the records of the zone selected by
+// filter, which lists them from Route53 one page at a time as the loop
+// consumes them, instead of holding the whole zone in memory:
+//
+//	for record, err := range provider.StreamRecords(ctx, zone, route53.RecordFilter{}) {
+//		if err != nil {
+//			return err
+//		}
+//		// ...
+//	}
+//
+// The records come in the order Route53 lists them, by name with the labels
+// reversed and then by type, unless they come from the RecordCacheTTL cache.
+// Breaking out of the loop stops the listing. An error is yielded once, as
+// the last element.
+func (p *Provider) StreamRecords(
+	ctx context.Context,
+	zone string,
+	filter RecordFilter,
+) iter.Seq2[libdns.Record, error] {
+	return func(yield func(libdns.Record, error) bool) {
+		spanCtx, end := p.startSpan(ctx, "StreamRecords", zoneAttributes(zone, nil))
+		spanCtx = p.withRecordCache(spanCtx)
+		stopped := false
+		err := p.scanRecords(spanCtx, zone, filter, func(record libdns.Record) bool {
+			stopped = !yield(record, nil)
+			return !stopped
+		})
+		end(&err)
+		if err != nil && !stopped {
+			yield(nil, err)
+		}
+	}
+}
+
+// scanRecords calls fn for each record of the zone selected by filter, in
+// the order Route53 lists them or from the cache, until it returns false.
+func (p *Provider) scanRecords(
+	ctx context.Context,
+	zone string,
+	filter RecordFilter,
+	fn func(record libdns.Record) bool,
+) error {
+	zone = absoluteZoneName(zone)
+	scan, err := newRecordScan(filter, zone)
+	if err != nil {
+		return err
+	}
+
+	zoneID, err := p.getZoneID(ctx, zone)
+	if err != nil {
+		return err
+	}
+
+	p.Logger.DebugContext(ctx, "listing filtered records",
+		"zone", zone, "start_name", scan.startName, "start_type", scan.startType)
+
+	var parseErr error
+	visit := func(set types.ResourceRecordSet) bool {
+		if !scan.matches(set, zone) {
+			return true
+		}
+
+		var parsed []libdns.Record
+		if parsed, parseErr = parseRecordSet(set, zone); parseErr != nil {
+			return false
+		}
+		for _, record := range parsed {
+			if p.hidesAlias(record) {
+				continue
+			}
+			if !fn(record) {
+				return false
+			}
+		}
+		return true
+	}
+
+	// the cache is not in the listing order, so it is searched whole
+	if cached, _, ok := p.recordCache.get(zoneID); ok && usesRecordCache(ctx) {
+		for _, set := range cached {
+			if !visit(set) {
+				break
+			}
+		}
+	} else {
+		err = p.scanRecordSets(ctx, scan.input(zoneID), func(set types.ResourceRecordSet) bool {
+			return !scan.past(set) && visit(set)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if parseErr != nil {
+		return fmt.Errorf("failed to parse record set: %w", parseErr)
+	}
+	return nil
+}
+
+// recordScan is the listing of a zone for a RecordFilter.
+type recordScan struct {
+	filter RecordFilter
+	// name is the lowercased relative name or pattern of the filter.
+	name string
+
+	// startName and startType are where the listing starts, if not at the
+	// beginning of the zone.
+	startName, startType string
+	// subtree, if set, is the reversed name whose subtree holds every
+	// match; the listing stops at the first name outside it, or at the
+	// first name other than it if exact.
+	subtree string
+	exact   bool
+}
+
+// newRecordScan returns the scan of zone for filter.
+func newRecordScan(filter RecordFilter, zone string) (*recordScan, error) {
+	scan := &recordScan{filter: filter}
+	if filter.Name == "" {
+		return scan, nil
+	}
+	scan.name = strings.ToLower(libdns.RelativeName(filter.Name, zone))
+
+	// the name whose subtree holds every match
+	var root string
+	switch filter.NameMatch {
+	case "", NameExact, NameSuffix:
+		// GetRecords returns names as Route53 escapes them, such as \052
+		// for a wildcard label
+		scan.name = unquote(scan.name)
+		root = scan.name
+	case NameWildcard:
+		if _, err := path.Match(scan.name, ""); err != nil {
+			return nil, fmt.Errorf("invalid name pattern %q: %w", filter.Name, err)
+		}
+		literal := scan.name[strings.LastIndexAny(scan.name, `*?[\`)+1:]
+		if !strings.HasPrefix(literal, ".") {
+			return scan, nil
+		}
+		root = literal[1:]
+	default:
+		return nil, fmt.Errorf("unknown name match %q", filter.NameMatch)
+	}
+
+	absolute := libdns.AbsoluteName(root, zone)
+	scan.startName = absolute
+	scan.subtree = reversedName(absolute)
+	if filter.NameMatch == "" || filter.NameMatch == NameExact {
+		scan.exact = true
+		if len(filter.Types) == 1 {
+			scan.startType = strings.ToUpper(filter.Types[0])
+		}
+	}
+	return scan, nil
+}
+
+// past reports whether the listing went past every match of the filter with
+// a record set, since Route53 lists them in the order of reversedName.
+func (s *recordScan) past(set types.ResourceRecordSet) bool {
+	if s.subtree == "" {
+		return false
+	}
+	name := reversedName(aws.ToString(set.Name))
+	if s.exact {
+		return name != s.subtree
+	}
+	return !strings.HasPrefix(name, s.subtree)
+}
+
+// input returns the first ListResourceRecordSets request of the scan.
+func (s *recordScan) input(zoneID string) *r53.ListResourceRecordSetsInput {
+	input := &r53.ListResourceRecordSetsInput{HostedZoneId: aws.String(zoneID)}
+	if s.startName != "" {
+		input.StartRecordName = aws.String(s.startName)
+	}
+	if s.startType != "" {
+		input.StartRecordType = types.RRType(s.startType)
+	}
+	return input
+}
+
+// matches reports whether a record set of zone matches the filter.
+func (s *recordScan) matches(set types.ResourceRecordSet, zone string) bool {
+	if s.filter.SetIdentifier != "" && aws.ToString(set.SetIdentifier) != s.filter.SetIdentifier {
+		return false
+	}
+	if len(s.filter.Types) > 0 && !slices.ContainsFunc(s.filter.Types, func(t string) bool {
+		return strings.EqualFold(t, string(set.Type))
+	}) {
+		return false
+	}
+	if s.filter.Name == "" {
+		return true
+	}
+
+	name := strings.ToLower(libdns.RelativeName(unquote(aws.ToString(set.Name)), zone))
+	switch s.filter.NameMatch {
+	case NameSuffix:
+		return s.name == "@" || name == s.name || strings.HasSuffix(name, "."+s.name)
+	case NameWildcard:
+		matched, _ := path.Match(s.name, name)
+		return matched
+	default:
+		return name == s.name
+	}
+}
+
+// reversedName returns an absolute name lowercased, with its labels reversed
+// and a trailing dot, as Route53 sorts record sets: www.example.com. gives
+// com.example.www. The names of a subtree are those starting with its
+// reversed root.
+func reversedName(name string) string {
+	labels := strings.Split(strings.ToLower(unquote(strings.TrimSuffix(name, "."))), ".")
+	slices.Reverse(labels)
+	return strings.Join(labels, ".") + "."
+}
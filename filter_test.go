@@ -0,0 +1,185 @@
+package route53 //nolint:testpackage // Testing internal functions
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/route53/types"
+)
+
+func TestGetRecordsFiltered(t *testing.T) {
+	// the listing stops past the subtree, so the truncated page is the last
+	// one requested
+	provider := &Provider{HostedZoneID: "Z1"}
+	canned := newCannedProvider(t, provider, cannedResponse{status: http.StatusOK, body: truncated(
+		listRecordSetsResponse(
+			recordSetXML("_acme-challenge.example.com.", "TXT", `"apex"`),
+			recordSetXML("a._acme-challenge.example.com.", "TXT", `"a"`),
+			recordSetXML("a._acme-challenge.example.com.", "CNAME", "a.example.net."),
+			recordSetXML("_acme-challenge-x.example.com.", "TXT", `"sibling"`),
+		),
+		"b.example.com.",
+	)})
+
+	records, err := provider.GetRecordsFiltered(context.Background(), "example.com",
+		RecordFilter{Name: "_acme-challenge", NameMatch: NameSuffix, Types: []string{"txt"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var got []string
+	for _, record := range records {
+		got = append(got, record.RR().Name+" "+record.RR().Data)
+	}
+	if strings.Join(got, ", ") != "_acme-challenge apex, a._acme-challenge a" {
+		t.Errorf("unexpected records %v", got)
+	}
+	if len(canned.requests) != 1 {
+		t.Fatalf("expected a single request, got %d", len(canned.requests))
+	}
+	query := canned.requests[0].URL.Query()
+	if query.Get("name") != "_acme-challenge.example.com." || query.Get("type") != "" {
+		t.Errorf("expected the listing to start at the subtree, got %v", query)
+	}
+}
+
+func TestStreamRecords(t *testing.T) {
+	pages := []cannedResponse{
+		{status: http.StatusOK, body: truncated(listRecordSetsResponse(
+			recordSetXML("a.example.com.", "TXT", `"1"`, `"2"`),
+		), "b.example.com.")},
+		{status: http.StatusOK, body: listRecordSetsResponse(
+			recordSetXML("b.example.com.", "A", "192.0.2.1"),
+		)},
+	}
+
+	provider := &Provider{HostedZoneID: "Z1"}
+	canned := newCannedProvider(t, provider, pages...)
+	var got []string
+	for record, err := range provider.StreamRecords(context.Background(), "example.com.", RecordFilter{}) {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		got = append(got, record.RR().Name+" "+record.RR().Data)
+	}
+	if strings.Join(got, ", ") != "a 1, a 2, b 192.0.2.1" {
+		t.Errorf("unexpected records %v", got)
+	}
+	if len(canned.requests) != 2 || canned.requests[1].URL.Query().Get("name") != "b.example.com." {
+		t.Errorf("expected the second page requested from b.example.com., got %d requests", len(canned.requests))
+	}
+
+	// breaking out of the loop stops the listing
+	canned = newCannedProvider(t, provider, pages...)
+	for range provider.StreamRecords(context.Background(), "example.com.", RecordFilter{}) {
+		break
+	}
+	if len(canned.requests) != 1 {
+		t.Errorf("expected a single page requested, got %d", len(canned.requests))
+	}
+
+	// an error ends the iteration
+	newCannedProvider(t, provider, pages[0])
+	var errs int
+	for _, err := range provider.StreamRecords(context.Background(), "example.com.", RecordFilter{}) {
+		if err != nil {
+			errs++
+		}
+	}
+	if errs != 1 {
+		t.Errorf("expected a single error, got %d", errs)
+	}
+}
+
+func TestRecordScan(t *testing.T) {
+	cases := []struct {
+		filter               RecordFilter
+		startName, startType string
+		match, skip          []string
+	}{
+		{
+			filter:    RecordFilter{Name: "www", Types: []string{"A"}},
+			startName: "www.example.com.", startType: "A",
+			match: []string{"www.example.com."},
+			skip:  []string{"a.www.example.com.", "example.com."},
+		},
+		{
+			filter:    RecordFilter{Name: "@", NameMatch: NameSuffix},
+			startName: "example.com.",
+			match:     []string{"example.com.", "a.b.example.com."},
+		},
+		{
+			filter:    RecordFilter{Name: "*.dev.example.com.", NameMatch: NameWildcard},
+			startName: "dev.example.com.",
+			match:     []string{"a.dev.example.com.", "a.b.dev.example.com."},
+			skip:      []string{"dev.example.com.", "a.prod.example.com."},
+		},
+		{
+			filter: RecordFilter{Name: "_acme-challenge.*", NameMatch: NameWildcard},
+			match:  []string{"_acme-challenge.www.example.com."},
+			skip:   []string{"www.example.com."},
+		},
+		{
+			filter: RecordFilter{Name: `\052`, Types: []string{"A"}},
+			match:  []string{`\052.example.com.`},
+			skip:   []string{"www.example.com."},
+		},
+		{
+			filter: RecordFilter{Name: "*", Types: []string{"A"}},
+			match:  []string{`\052.example.com.`},
+			skip:   []string{"www.example.com."},
+		},
+	}
+	for _, c := range cases {
+		scan, err := newRecordScan(c.filter, "example.com.")
+		if err != nil {
+			t.Fatalf("%+v: unexpected error: %v", c.filter, err)
+		}
+		if c.startName != "" && (scan.startName != c.startName || scan.startType != c.startType) {
+			t.Errorf("%+v: expected to start at %s %s, got %s %s",
+				c.filter, c.startName, c.startType, scan.startName, scan.startType)
+		}
+		for _, name := range c.match {
+			if !scan.matches(recordSet(name, "A", ""), "example.com.") {
+				t.Errorf("%+v: expected %s to match", c.filter, name)
+			}
+		}
+		for _, name := range c.skip {
+			if scan.matches(recordSet(name, "A", ""), "example.com.") {
+				t.Errorf("%+v: expected %s not to match", c.filter, name)
+			}
+		}
+	}
+
+	scan, err := newRecordScan(RecordFilter{SetIdentifier: "blue"}, "example.com.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if scan.matches(recordSet("www.example.com.", "A", "green"), "example.com.") ||
+		!scan.matches(recordSet("www.example.com.", "A", "blue"), "example.com.") {
+		t.Error("expected to match the set identifier")
+	}
+
+	if _, err = newRecordScan(RecordFilter{Name: "[", NameMatch: NameWildcard}, "example.com."); err == nil {
+		t.Error("expected an invalid pattern to fail")
+	}
+}
+
+// recordSet returns a ResourceRecordSet without values.
+func recordSet(name, recordType, setIdentifier string) types.ResourceRecordSet {
+	set := types.ResourceRecordSet{Name: aws.String(name), Type: types.RRType(recordType)}
+	if setIdentifier != "" {
+		set.SetIdentifier = aws.String(setIdentifier)
+	}
+	return set
+}
+
+// truncated makes a ListResourceRecordSets response truncated, continuing at
+// next.
+func truncated(response, next string) string {
+	return strings.Replace(response, "<IsTruncated>false</IsTruncated>",
+		"<IsTruncated>true</IsTruncated><NextRecordName>"+next+"</NextRecordName><NextRecordType>A</NextRecordType>", 1)
+}
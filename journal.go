@@ -0,0 +1,138 @@
+package route53
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+)
+
+// Journal receives an entry for every change batch the provider submits to
+// Route53, for audit purposes. Record is called once Route53 has accepted the
+// change, before waiting for it to be INSYNC; an error is logged but does not
+// fail the call, since the change has been made.
+//
+// Record may be called concurrently.
+type Journal interface {
+	Record(ctx context.Context, entry JournalEntry) error
+}
+
+// JournalEntry describes a change batch submitted to Route53.
+type JournalEntry struct {
+	// Time is when the change was submitted, as reported by Route53.
+	Time time.Time `json:"time"`
+
+	// Actor identifies who made the change, as set with WithJournalActor.
+	Actor string `json:"actor,omitempty"`
+
+	// Operation is the record operation that made the change, such as
+	// OperationAppend. It is empty for the deletions made by DeleteZone.
+	Operation string `json:"operation,omitempty"`
+
+	// Zone is the zone name.
+	Zone string `json:"zone"`
+
+	// HostedZoneID is the ID of the hosted zone.
+	HostedZoneID string `json:"hosted_zone_id"`
+
+	// ChangeID is the Route53 change ID.
+	ChangeID string `json:"change_id"`
+
+	// RecordSets are the record sets the change batch modified.
+	RecordSets []RecordSetChange `json:"record_sets"`
+}
+
+// RecordSetChange is the change of a single record set from one set of
+// values to another.
+type RecordSetChange struct {
+	// Name is the record set name, relative to the zone.
+	Name string `json:"name"`
+
+	// Type is the record set type.
+	Type string `json:"type"`
+
+	// Before are the values of the record set before the change; empty if
+	// it did not exist.
+	Before Records `json:"before"`
+
+	// After are the values of the record set after the change; empty if it
+	// was deleted.
+	After Records `json:"after"`
+}
+
+// WithJournalActor returns a context that makes the changes submitted with it
+// appear in the Journal as made by actor, such as a user or service name.
+func WithJournalActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, contextKeyJournalActor, actor)
+}
+
+// FileJournal is a Journal appending entries to a file as JSON lines. It is
+// safe for concurrent use within a process.
+type FileJournal struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewFileJournal returns a Journal appending to the file at path, which is
+// created if needed.
+func NewFileJournal(path string) *FileJournal {
+	return &FileJournal{path: path}
+}
+
+// Record appends entry to the file as a single JSON line.
+func (j *FileJournal) Record(_ context.Context, entry JournalEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	f, err := os.OpenFile(j.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(line); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// journal reports a submitted change to the Journal, if any.
+func (p *Provider) journal(ctx context.Context, change Change) {
+	if p.Journal == nil {
+		return
+	}
+
+	actor, _ := ctx.Value(contextKeyJournalActor).(string)
+	operation, _ := ctx.Value(contextKeyOperation).(string)
+	entry := JournalEntry{
+		Time:         change.SubmittedAt,
+		Actor:        actor,
+		Operation:    operation,
+		Zone:         change.Zone,
+		HostedZoneID: change.HostedZoneID,
+		ChangeID:     change.ID,
+		RecordSets:   change.RecordSets,
+	}
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+
+	if err := p.Journal.Record(ctx, entry); err != nil {
+		p.Logger.WarnContext(ctx, "failed to record change in journal",
+			"zone", change.Zone, "change_id", change.ID, "error", err)
+	}
+}
+
+// needsBefore reports whether the values of record sets must be read before
+// changing them: for the Journal, or so the changes reported to the context's
+// ChangeRecorder can be reverted.
+func (p *Provider) needsBefore(ctx context.Context) bool {
+	_, recording := ctx.Value(contextKeyChangeRecorder).(*ChangeRecorder)
+	return p.Journal != nil || recording
+}
@@ -0,0 +1,125 @@
+package route53 //nolint:testpackage // Testing internal functions
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+// memoryJournal keeps journal entries in memory.
+type memoryJournal struct {
+	mu      sync.Mutex
+	entries []JournalEntry
+	err     error
+}
+
+func (j *memoryJournal) Record(_ context.Context, entry JournalEntry) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.entries = append(j.entries, entry)
+	return j.err
+}
+
+func TestProviderJournal(t *testing.T) {
+	journal := &memoryJournal{}
+	provider := &Provider{Journal: journal}
+	provider.init(context.TODO())
+
+	before := []libdns.Record{libdns.TXT{Name: "_acme-challenge", TTL: time.Minute, Text: "old"}}
+	after := []libdns.Record{libdns.TXT{Name: "_acme-challenge", TTL: time.Minute, Text: "new"}}
+	submitted := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	ctx := WithJournalActor(withOperation(context.Background(), OperationSet), "deploy-bot")
+	provider.journal(ctx, Change{
+		ID:           "/change/C1",
+		SubmittedAt:  submitted,
+		Zone:         "example.com.",
+		HostedZoneID: "Z123",
+		RecordSets:   []RecordSetChange{{Name: "_acme-challenge", Type: "TXT", Before: before, After: after}},
+	})
+
+	if len(journal.entries) != 1 {
+		t.Fatalf("expected 1 entry, got %d", len(journal.entries))
+	}
+	entry := journal.entries[0]
+	if entry.Actor != "deploy-bot" || entry.Operation != OperationSet || entry.Zone != "example.com." ||
+		entry.HostedZoneID != "Z123" || entry.ChangeID != "/change/C1" || !entry.Time.Equal(submitted) {
+		t.Errorf("unexpected entry: %+v", entry)
+	}
+
+	// a failing journal does not fail the change
+	journal.err = errors.New("disk full")
+	provider.journal(ctx, Change{ID: "/change/C2", Zone: "example.com."})
+	if len(journal.entries) != 2 || journal.entries[1].Time.IsZero() {
+		t.Errorf("expected a second entry with a time, got %+v", journal.entries)
+	}
+
+	if !provider.needsBefore(context.Background()) || (&Provider{}).needsBefore(context.Background()) {
+		t.Error("expected previous values to be read with a journal")
+	}
+	if !(&Provider{}).needsBefore(WithChangeRecorder(context.Background(), &ChangeRecorder{})) {
+		t.Error("expected previous values to be read with a change recorder")
+	}
+}
+
+func TestFileJournal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal.jsonl")
+	provider := &Provider{JournalFile: path}
+	provider.init(context.TODO())
+
+	if _, ok := provider.Journal.(*FileJournal); !ok {
+		t.Fatalf("expected JournalFile to set up a FileJournal, got %T", provider.Journal)
+	}
+
+	var wg sync.WaitGroup
+	for i := range 10 {
+		wg.Go(func() {
+			err := provider.Journal.Record(context.Background(), JournalEntry{
+				Zone: "example.com.",
+				RecordSets: []RecordSetChange{{
+					Name:  "www",
+					Type:  "A",
+					After: Records{libdns.Address{Name: "www", TTL: time.Minute, IP: netip.AddrFrom4([4]byte{192, 0, 2, byte(i)})}},
+				}},
+			})
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+	wg.Wait()
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	lines := 0
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var entry JournalEntry
+		if err = json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			t.Fatalf("line %d: %v", lines+1, err)
+		}
+		if len(entry.RecordSets) != 1 || len(entry.RecordSets[0].After) != 1 {
+			t.Errorf("line %d: unexpected entry %+v", lines+1, entry)
+		}
+		if _, ok := entry.RecordSets[0].After[0].(libdns.Address); !ok {
+			t.Errorf("line %d: expected an address record, got %T", lines+1, entry.RecordSets[0].After[0])
+		}
+		lines++
+	}
+	if lines != 10 {
+		t.Errorf("expected 10 lines, got %d", lines)
+	}
+}
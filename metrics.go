@@ -0,0 +1,61 @@
+package route53
+
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/route53/types"
+)
+
+// Metrics receives measurements of the provider's activity, to alert on DNS
+// automation failing or slowing down without OpenTelemetry. The prommetrics
+// package implements it for Prometheus.
+//
+// The methods are called concurrently, on the hot path of the provider; they
+// must not block.
+type Metrics interface {
+	// CountChange counts a record set change accepted by Route53, by action
+	// (CREATE, UPSERT or DELETE) and record type.
+	CountChange(action, recordType string)
+
+	// ObserveAPICall records the duration of a Route53 API call, including
+	// its retries, by operation, such as ChangeResourceRecordSets. code is
+	// empty on success, and otherwise the Route53 error code, such as
+	// Throttling, or "timeout", "canceled" or "_OTHER" for failures without
+	// one.
+	ObserveAPICall(operation, code string, duration time.Duration)
+
+	// ObserveSyncWait records the time spent waiting for a change to be
+	// INSYNC, and whether it got there.
+	ObserveSyncWait(duration time.Duration, synced bool)
+
+	// AddLockWaiters adds delta to the number of callers waiting for another
+	// caller to finish with the same record set: 1 when a caller starts
+	// waiting, -1 when it gets its turn.
+	AddLockWaiters(delta int)
+}
+
+// nopMetrics is the Metrics of a provider without one.
+type nopMetrics struct{}
+
+func (nopMetrics) CountChange(string, string)                   {}
+func (nopMetrics) ObserveAPICall(string, string, time.Duration) {}
+func (nopMetrics) ObserveSyncWait(time.Duration, bool)          {}
+func (nopMetrics) AddLockWaiters(int)                           {}
+
+// metrics returns the Metrics of the provider, which does nothing if unset.
+func (p *Provider) metrics() Metrics {
+	if p.Metrics == nil {
+		return nopMetrics{}
+	}
+	return p.Metrics
+}
+
+// countChanges counts the changes of a batch accepted by Route53.
+func (p *Provider) countChanges(changes []types.Change) {
+	m := p.metrics()
+	for _, change := range changes {
+		if change.ResourceRecordSet != nil {
+			m.CountChange(string(change.Action), string(change.ResourceRecordSet.Type))
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package route53 //nolint:testpackage // Testing internal functions
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/route53/types"
+)
+
+// recordingMetrics keeps the measurements it receives.
+type recordingMetrics struct {
+	mu          sync.Mutex
+	changes     map[string]int
+	lockWaiters int
+	maxWaiters  int
+}
+
+func (m *recordingMetrics) CountChange(action, recordType string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.changes == nil {
+		m.changes = make(map[string]int)
+	}
+	m.changes[action+" "+recordType]++
+}
+
+func (m *recordingMetrics) ObserveAPICall(string, string, time.Duration) {}
+
+func (m *recordingMetrics) ObserveSyncWait(time.Duration, bool) {}
+
+func (m *recordingMetrics) AddLockWaiters(delta int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.lockWaiters += delta
+	m.maxWaiters = max(m.maxWaiters, m.lockWaiters)
+}
+
+func TestLockWaitersMetric(t *testing.T) {
+	metrics := &recordingMetrics{}
+	provider := &Provider{Metrics: metrics, Logger: slog.New(slog.DiscardHandler)}
+	key := setLockKey{zoneID: "Z1", name: "_acme-challenge", recordType: "TXT"}
+
+	unlock, err := provider.lockSet(context.Background(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	waiting := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		close(waiting)
+		if release, lockErr := provider.lockSet(context.Background(), key); lockErr == nil {
+			release()
+		}
+		close(done)
+	}()
+	<-waiting
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		metrics.mu.Lock()
+		n := metrics.lockWaiters
+		metrics.mu.Unlock()
+		if n == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected a waiter to be counted")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	unlock()
+	<-done
+	if metrics.lockWaiters != 0 || metrics.maxWaiters != 1 {
+		t.Errorf("expected the waiter counted once then released, got %d (max %d)",
+			metrics.lockWaiters, metrics.maxWaiters)
+	}
+
+	// an uncontended lock is not counted
+	unlock, err = provider.lockSet(context.Background(), setLockKey{zoneID: "Z1", name: "www", recordType: "A"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	unlock()
+	if metrics.maxWaiters != 1 {
+		t.Errorf("expected no waiter for an uncontended lock, got max %d", metrics.maxWaiters)
+	}
+}
+
+func TestCountChanges(t *testing.T) {
+	metrics := &recordingMetrics{}
+	provider := &Provider{Metrics: metrics}
+
+	provider.countChanges([]types.Change{
+		{Action: types.ChangeActionUpsert, ResourceRecordSet: &types.ResourceRecordSet{Name: aws.String("a."), Type: types.RRTypeTxt}},
+		{Action: types.ChangeActionUpsert, ResourceRecordSet: &types.ResourceRecordSet{Name: aws.String("b."), Type: types.RRTypeTxt}},
+		{Action: types.ChangeActionDelete, ResourceRecordSet: &types.ResourceRecordSet{Name: aws.String("c."), Type: types.RRTypeA}},
+	})
+	if metrics.changes["UPSERT TXT"] != 2 || metrics.changes["DELETE A"] != 1 {
+		t.Errorf("unexpected counts: %v", metrics.changes)
+	}
+
+	// without Metrics nothing happens
+	(&Provider{}).countChanges([]types.Change{{Action: types.ChangeActionCreate}})
+}
@@ -0,0 +1,110 @@
+// Package prommetrics exposes the metrics of a route53.Provider to
+// Prometheus:
+//
+//	collector := prommetrics.New("")
+//	prometheus.MustRegister(collector)
+//	provider := &route53.Provider{Metrics: collector}
+//
+// The metrics are:
+//
+//   - route53_changes_total{action,type}: record set changes accepted by
+//     Route53.
+//   - route53_api_call_duration_seconds{operation,code}: duration of Route53
+//     API calls, including their retries; code is the error code of failed
+//     calls, empty on success.
+//   - route53_sync_wait_duration_seconds{synced}: time spent waiting for
+//     changes to be INSYNC.
+//   - route53_lock_waiters: callers waiting for another caller to finish
+//     with the same record set.
+package prommetrics
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/libdns/route53"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Collector is both a route53.Metrics and a prometheus.Collector. A single
+// Collector can be shared by several providers.
+type Collector struct {
+	changes     *prometheus.CounterVec
+	apiCalls    *prometheus.HistogramVec
+	syncWait    *prometheus.HistogramVec
+	lockWaiters prometheus.Gauge
+}
+
+var (
+	_ route53.Metrics      = (*Collector)(nil)
+	_ prometheus.Collector = (*Collector)(nil)
+)
+
+// New returns a Collector whose metric names are prefixed with namespace
+// and an underscore, if not empty.
+func New(namespace string) *Collector {
+	return &Collector{
+		changes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "route53",
+			Name:      "changes_total",
+			Help:      "Record set changes accepted by Route53, by action and record type.",
+		}, []string{"action", "type"}),
+		apiCalls: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "route53",
+			Name:      "api_call_duration_seconds",
+			Help:      "Duration of Route53 API calls including retries, by operation and error code.",
+			Buckets:   prometheus.ExponentialBuckets(0.05, 2, 10),
+		}, []string{"operation", "code"}),
+		syncWait: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "route53",
+			Name:      "sync_wait_duration_seconds",
+			Help:      "Time spent waiting for Route53 changes to be INSYNC.",
+			Buckets:   prometheus.ExponentialBuckets(1, 2, 10),
+		}, []string{"synced"}),
+		lockWaiters: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "route53",
+			Name:      "lock_waiters",
+			Help:      "Callers waiting for another caller to finish with the same record set.",
+		}),
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	c.changes.Describe(ch)
+	c.apiCalls.Describe(ch)
+	c.syncWait.Describe(ch)
+	c.lockWaiters.Describe(ch)
+}
+
+// Collect implements prometheus.Collector.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.changes.Collect(ch)
+	c.apiCalls.Collect(ch)
+	c.syncWait.Collect(ch)
+	c.lockWaiters.Collect(ch)
+}
+
+// CountChange implements route53.Metrics.
+func (c *Collector) CountChange(action, recordType string) {
+	c.changes.WithLabelValues(action, recordType).Inc()
+}
+
+// ObserveAPICall implements route53.Metrics.
+func (c *Collector) ObserveAPICall(operation, code string, duration time.Duration) {
+	c.apiCalls.WithLabelValues(operation, code).Observe(duration.Seconds())
+}
+
+// ObserveSyncWait implements route53.Metrics.
+func (c *Collector) ObserveSyncWait(duration time.Duration, synced bool) {
+	c.syncWait.WithLabelValues(strconv.FormatBool(synced)).Observe(duration.Seconds())
+}
+
+// AddLockWaiters implements route53.Metrics.
+func (c *Collector) AddLockWaiters(delta int) {
+	c.lockWaiters.Add(float64(delta))
+}
@@ -0,0 +1,58 @@
+package prommetrics
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestCollector(t *testing.T) {
+	c := New("dns")
+	registry := prometheus.NewPedanticRegistry()
+	if err := registry.Register(c); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	c.CountChange("UPSERT", "TXT")
+	c.CountChange("UPSERT", "TXT")
+	c.CountChange("DELETE", "TXT")
+	c.ObserveAPICall("ChangeResourceRecordSets", "", 200*time.Millisecond)
+	c.ObserveAPICall("ChangeResourceRecordSets", "Throttling", time.Second)
+	c.ObserveSyncWait(30*time.Second, true)
+	c.AddLockWaiters(1)
+	c.AddLockWaiters(1)
+	c.AddLockWaiters(-1)
+
+	expected := `
+# HELP dns_route53_changes_total Record set changes accepted by Route53, by action and record type.
+# TYPE dns_route53_changes_total counter
+dns_route53_changes_total{action="DELETE",type="TXT"} 1
+dns_route53_changes_total{action="UPSERT",type="TXT"} 2
+# HELP dns_route53_lock_waiters Callers waiting for another caller to finish with the same record set.
+# TYPE dns_route53_lock_waiters gauge
+dns_route53_lock_waiters 1
+`
+	err := testutil.GatherAndCompare(registry, strings.NewReader(expected),
+		"dns_route53_changes_total", "dns_route53_lock_waiters")
+	if err != nil {
+		t.Error(err)
+	}
+
+	if n := testutil.CollectAndCount(c, "dns_route53_api_call_duration_seconds"); n != 2 {
+		t.Errorf("expected 2 API call series, got %d", n)
+	}
+	if n := testutil.CollectAndCount(c, "dns_route53_sync_wait_duration_seconds"); n != 1 {
+		t.Errorf("expected 1 sync wait series, got %d", n)
+	}
+
+	problems, err := testutil.GatherAndLint(registry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, problem := range problems {
+		t.Errorf("lint: %s: %s", problem.Metric, problem.Text)
+	}
+}
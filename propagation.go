@@ -0,0 +1,424 @@
+package route53
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	r53 "github.com/aws/aws-sdk-go-v2/service/route53"
+	"github.com/aws/aws-sdk-go-v2/service/route53/types"
+	"github.com/libdns/libdns"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+const (
+	// defaultDNSPropagationTimeout is the default limit for DNS propagation checks.
+	defaultDNSPropagationTimeout = 2 * time.Minute
+	// defaultDNSPropagationInterval is the default delay between two rounds of queries.
+	defaultDNSPropagationInterval = 5 * time.Second
+	// dnsQueryTimeout bounds a single query to a single name server.
+	dnsQueryTimeout = 5 * time.Second
+	// dnsPort is the port used for name servers given without one.
+	dnsPort = "53"
+	// maxUDPMessageSize is the largest DNS message accepted over UDP.
+	maxUDPMessageSize = 4096
+)
+
+// ErrDNSPropagationTimeout is returned when the authoritative name servers
+// did not serve the expected values before DNSPropagationTimeout elapsed.
+var ErrDNSPropagationTimeout = errors.New("route53: timed out waiting for DNS propagation")
+
+// WaitForDNSPropagation queries the zone's authoritative name servers directly
+// until every one of them serves all the given records.
+//
+// The name servers are DNSPropagationResolvers if set, otherwise the targets of
+// the zone's apex NS record set. Records of types that cannot be compared
+// (anything other than A, AAAA, CNAME, TXT, MX, NS, SRV and CAA) are not
+// checked.
+func (p *Provider) WaitForDNSPropagation(ctx context.Context, zone string, records []libdns.Record) (err error) {
+	ctx, end := p.startSpan(ctx, "WaitForDNSPropagation", zoneAttributes(zone, records))
+	defer end(&err)
+
+	return p.waitForDNS(ctx, zone, records, true)
+}
+
+// WaitForDNSRemoval is the counterpart of WaitForDNSPropagation: it waits until
+// none of the authoritative name servers serve any of the given records.
+func (p *Provider) WaitForDNSRemoval(ctx context.Context, zone string, records []libdns.Record) (err error) {
+	ctx, end := p.startSpan(ctx, "WaitForDNSRemoval", zoneAttributes(zone, records))
+	defer end(&err)
+
+	return p.waitForDNS(ctx, zone, records, false)
+}
+
+// waitForDNS polls every name server until the records are present (or
+// absent) on all of them.
+func (p *Provider) waitForDNS(ctx context.Context, zone string, records []libdns.Record, present bool) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	servers, err := p.propagationServers(ctx, zone)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, p.DNSPropagationTimeout)
+	defer cancel()
+
+	expected := expectedValues(zone, records)
+
+	p.Logger.DebugContext(ctx, "waiting for DNS propagation",
+		"zone", zone,
+		"servers", servers,
+		"record_sets", len(expected),
+		"present", present)
+
+	for attempt := 1; ; attempt++ {
+		pending := p.pendingDNSChecks(ctx, servers, expected, present)
+		if pending == 0 {
+			p.Logger.DebugContext(ctx, "DNS propagation complete", "zone", zone, "attempts", attempt)
+			return nil
+		}
+
+		p.Logger.DebugContext(ctx, "DNS propagation pending",
+			"zone", zone, "attempt", attempt, "pending_checks", pending)
+
+		select {
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return fmt.Errorf("%w: %d checks pending for zone %s", ErrDNSPropagationTimeout, pending, zone)
+			}
+			return ctx.Err()
+		case <-time.After(p.DNSPropagationInterval):
+		}
+	}
+}
+
+// pendingDNSChecks runs one round of queries and returns how many
+// (server, name, type) checks do not match the expectation yet. Query errors
+// count as pending: the server may simply be unreachable for a moment.
+func (p *Provider) pendingDNSChecks(
+	ctx context.Context,
+	servers []string,
+	expected map[recordSetKey][]string,
+	present bool,
+) int {
+	pending := 0
+	for _, server := range servers {
+		for _, key := range sortedRecordSetKeys(expected) {
+			want := expected[key]
+			got, err := queryDNS(ctx, server, key.name, key.recordType)
+			if err != nil {
+				p.Logger.DebugContext(ctx, "DNS propagation query failed",
+					"server", server, "name", key.name, "type", key.recordType, "error", err)
+				pending++
+				continue
+			}
+			if !propagated(got, want, present) {
+				pending++
+			}
+		}
+	}
+	return pending
+}
+
+// propagated reports whether the served values satisfy the expectation:
+// every wanted value served if present, none of them served otherwise.
+func propagated(got, want []string, present bool) bool {
+	served := make(map[string]bool, len(got))
+	for _, v := range got {
+		served[v] = true
+	}
+	for _, v := range want {
+		if served[v] != present {
+			return false
+		}
+	}
+	return true
+}
+
+// expectedValues groups the normalized record data by absolute name and type,
+// skipping records whose type cannot be compared.
+func expectedValues(zone string, records []libdns.Record) map[recordSetKey][]string {
+	expected := make(map[recordSetKey][]string)
+	for _, record := range records {
+		rr := record.RR()
+		value, ok := normalizeRecordData(rr.Type, rr.Data)
+		if !ok {
+			continue
+		}
+		key := recordSetKey{name: libdns.AbsoluteName(rr.Name, zone), recordType: rr.Type}
+		expected[key] = append(expected[key], value)
+	}
+	return expected
+}
+
+// propagationServers returns the name server addresses to query, either the
+// configured DNSPropagationResolvers or the zone's apex NS targets.
+func (p *Provider) propagationServers(ctx context.Context, zone string) ([]string, error) {
+	hosts := p.DNSPropagationResolvers
+	if len(hosts) == 0 {
+		zoneID, err := p.getZoneID(ctx, zone)
+		if err != nil {
+			return nil, err
+		}
+		hosts, err = p.apexNameServers(ctx, zoneID, zone)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	servers := make([]string, 0, len(hosts))
+	for _, host := range hosts {
+		if _, _, err := net.SplitHostPort(host); err != nil {
+			host = net.JoinHostPort(strings.TrimSuffix(host, "."), dnsPort)
+		}
+		servers = append(servers, host)
+	}
+	return servers, nil
+}
+
+// apexNameServers reads the zone's apex NS record set. These are the Route53
+// name servers the zone is delegated to.
+func (p *Provider) apexNameServers(ctx context.Context, zoneID, zone string) ([]string, error) {
+	out, err := p.client.ListResourceRecordSets(ctx, &r53.ListResourceRecordSetsInput{
+		HostedZoneId:    aws.String(zoneID),
+		StartRecordName: aws.String(zone),
+		StartRecordType: types.RRTypeNs,
+		MaxItems:        aws.Int32(1),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, set := range out.ResourceRecordSets {
+		if set.Type != types.RRTypeNs || !strings.EqualFold(aws.ToString(set.Name), zone) {
+			continue
+		}
+		servers := make([]string, 0, len(set.ResourceRecords))
+		for _, rr := range set.ResourceRecords {
+			servers = append(servers, aws.ToString(rr.Value))
+		}
+		return servers, nil
+	}
+
+	return nil, fmt.Errorf("no apex NS record set found in zone %s", zone)
+}
+
+// queryDNS asks server for the records of the given name and type and returns
+// their data normalized with normalizeRecordData. It uses UDP and falls back
+// to TCP when the answer is truncated.
+func queryDNS(ctx context.Context, server, name, recordType string) ([]string, error) {
+	qtype, ok := dnsType(recordType)
+	if !ok {
+		return nil, fmt.Errorf("unsupported record type %s", recordType)
+	}
+
+	qname, err := dnsmessage.NewName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	var idBytes [2]byte
+	if _, err = rand.Read(idBytes[:]); err != nil {
+		return nil, err
+	}
+	query := dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: binary.BigEndian.Uint16(idBytes[:])},
+		Questions: []dnsmessage.Question{{Name: qname, Type: qtype, Class: dnsmessage.ClassINET}},
+	}
+	packed, err := query.Pack()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, dnsQueryTimeout)
+	defer cancel()
+
+	resp, err := exchangeDNS(ctx, "udp", server, packed)
+	if err == nil && resp.Truncated {
+		resp, err = exchangeDNS(ctx, "tcp", server, packed)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if resp.ID != query.ID {
+		return nil, fmt.Errorf("DNS response ID mismatch from %s", server)
+	}
+	if resp.RCode != dnsmessage.RCodeSuccess && resp.RCode != dnsmessage.RCodeNameError {
+		return nil, fmt.Errorf("DNS query for %s %s to %s failed: %s", name, recordType, server, resp.RCode)
+	}
+
+	var values []string
+	for _, answer := range resp.Answers {
+		if answer.Header.Type != qtype || !strings.EqualFold(answer.Header.Name.String(), qname.String()) {
+			continue
+		}
+		if value, ok := formatResource(answer.Body); ok {
+			values = append(values, value)
+		}
+	}
+	return values, nil
+}
+
+// exchangeDNS sends a packed query to server and returns the parsed response.
+func exchangeDNS(ctx context.Context, network, server string, packed []byte) (*dnsmessage.Message, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, network, server)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		if err = conn.SetDeadline(deadline); err != nil {
+			return nil, err
+		}
+	}
+
+	buf := make([]byte, maxUDPMessageSize)
+	var n int
+	if network == "tcp" {
+		// DNS over TCP prefixes every message with its two-byte length
+		framed := binary.BigEndian.AppendUint16(nil, uint16(len(packed))) //nolint:gosec // queries are tiny
+		if _, err = conn.Write(append(framed, packed...)); err != nil {
+			return nil, err
+		}
+		var length [2]byte
+		if _, err = io.ReadFull(conn, length[:]); err != nil {
+			return nil, err
+		}
+		buf = make([]byte, binary.BigEndian.Uint16(length[:]))
+		n, err = io.ReadFull(conn, buf)
+	} else {
+		if _, err = conn.Write(packed); err != nil {
+			return nil, err
+		}
+		n, err = conn.Read(buf)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var msg dnsmessage.Message
+	if err = msg.Unpack(buf[:n]); err != nil {
+		return nil, err
+	}
+	return &msg, nil
+}
+
+// formatResource renders a resource body in the libdns RR data format,
+// normalized the same way normalizeRecordData normalizes expected values.
+func formatResource(body dnsmessage.ResourceBody) (string, bool) {
+	switch b := body.(type) {
+	case *dnsmessage.AResource:
+		return netip.AddrFrom4(b.A).String(), true
+	case *dnsmessage.AAAAResource:
+		return netip.AddrFrom16(b.AAAA).String(), true
+	case *dnsmessage.CNAMEResource:
+		return normalizeName(b.CNAME.String()), true
+	case *dnsmessage.NSResource:
+		return normalizeName(b.NS.String()), true
+	case *dnsmessage.MXResource:
+		return strconv.Itoa(int(b.Pref)) + " " + normalizeName(b.MX.String()), true
+	case *dnsmessage.SRVResource:
+		return fmt.Sprintf("%d %d %d %s", b.Priority, b.Weight, b.Port, normalizeName(b.Target.String())), true
+	case *dnsmessage.TXTResource:
+		return strings.Join(b.TXT, ""), true
+	case *dnsmessage.UnknownResource:
+		if b.Type == dnsTypeCAA {
+			return formatCAA(b.Data)
+		}
+	}
+	return "", false
+}
+
+// formatCAA decodes CAA wire data (RFC 8659: flags, tag length, tag, value).
+func formatCAA(data []byte) (string, bool) {
+	const header = 2
+	if len(data) < header || len(data) < header+int(data[1]) {
+		return "", false
+	}
+	tag := string(data[header : header+int(data[1])])
+	value := string(data[header+int(data[1]):])
+	return fmt.Sprintf("%d %s %q", data[0], strings.ToLower(tag), value), true
+}
+
+// normalizeRecordData brings libdns RR data into the canonical form used to
+// compare it with what name servers serve: lower-cased absolute host names and
+// canonical IP addresses. It returns false for types that cannot be compared.
+func normalizeRecordData(recordType, data string) (string, bool) {
+	switch recordType {
+	case "A", "AAAA":
+		addr, err := netip.ParseAddr(data)
+		if err != nil {
+			return "", false
+		}
+		return addr.String(), true
+	case "CNAME", "NS":
+		return normalizeName(data), true
+	case "MX", "SRV":
+		fields := strings.Fields(data)
+		if len(fields) == 0 {
+			return "", false
+		}
+		fields[len(fields)-1] = normalizeName(fields[len(fields)-1])
+		return strings.Join(fields, " "), true
+	case "CAA":
+		var flags uint8
+		var tag, value string
+		if _, err := fmt.Sscanf(data, "%d %s %q", &flags, &tag, &value); err != nil {
+			return "", false
+		}
+		return fmt.Sprintf("%d %s %q", flags, strings.ToLower(tag), value), true
+	case "TXT":
+		return data, true
+	}
+	return "", false
+}
+
+// normalizeName lower-cases a host name and makes it absolute.
+func normalizeName(name string) string {
+	name = strings.ToLower(name)
+	if !strings.HasSuffix(name, ".") {
+		name += "."
+	}
+	return name
+}
+
+// dnsTypeCAA is the CAA record type, which dnsmessage has no constant for.
+const dnsTypeCAA dnsmessage.Type = 257
+
+// dnsType maps the record types the propagation checker can compare to their
+// wire values.
+func dnsType(recordType string) (dnsmessage.Type, bool) {
+	switch recordType {
+	case "A":
+		return dnsmessage.TypeA, true
+	case "AAAA":
+		return dnsmessage.TypeAAAA, true
+	case "CNAME":
+		return dnsmessage.TypeCNAME, true
+	case "NS":
+		return dnsmessage.TypeNS, true
+	case "MX":
+		return dnsmessage.TypeMX, true
+	case "SRV":
+		return dnsmessage.TypeSRV, true
+	case "TXT":
+		return dnsmessage.TypeTXT, true
+	case "CAA":
+		return dnsTypeCAA, true
+	}
+	return 0, false
+}
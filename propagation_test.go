@@ -0,0 +1,155 @@
+package route53 //nolint:testpackage // Testing internal functions
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/netip"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/libdns/libdns"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// fakeNameServer is a minimal authoritative DNS server stand-in answering
+// queries from an in-memory table keyed by question.
+type fakeNameServer struct {
+	conn    net.PacketConn
+	mu      sync.Mutex
+	answers map[dnsmessage.Question][]dnsmessage.ResourceBody
+}
+
+func newFakeNameServer(t *testing.T) *fakeNameServer {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	s := &fakeNameServer{conn: conn, answers: make(map[dnsmessage.Question][]dnsmessage.ResourceBody)}
+	t.Cleanup(func() { _ = conn.Close() })
+	go s.serve()
+	return s
+}
+
+func (s *fakeNameServer) set(name string, qtype dnsmessage.Type, bodies ...dnsmessage.ResourceBody) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	q := dnsmessage.Question{Name: dnsmessage.MustNewName(name), Type: qtype, Class: dnsmessage.ClassINET}
+	s.answers[q] = bodies
+}
+
+func (s *fakeNameServer) serve() {
+	buf := make([]byte, maxUDPMessageSize)
+	for {
+		n, addr, err := s.conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		var query dnsmessage.Message
+		if err = query.Unpack(buf[:n]); err != nil || len(query.Questions) != 1 {
+			continue
+		}
+		q := query.Questions[0]
+		resp := dnsmessage.Message{
+			Header:    dnsmessage.Header{ID: query.ID, Response: true, Authoritative: true},
+			Questions: query.Questions,
+		}
+		s.mu.Lock()
+		for _, body := range s.answers[q] {
+			resp.Answers = append(resp.Answers, dnsmessage.Resource{
+				Header: dnsmessage.ResourceHeader{Name: q.Name, Class: dnsmessage.ClassINET, TTL: 60},
+				Body:   body,
+			})
+		}
+		s.mu.Unlock()
+		packed, err := resp.Pack()
+		if err != nil {
+			continue
+		}
+		_, _ = s.conn.WriteTo(packed, addr)
+	}
+}
+
+func testPropagationProvider(server *fakeNameServer) *Provider {
+	p := &Provider{
+		DNSPropagationResolvers: []string{server.conn.LocalAddr().String()},
+		DNSPropagationInterval:  10 * time.Millisecond,
+		DNSPropagationTimeout:   time.Second,
+	}
+	p.init(context.TODO())
+	return p
+}
+
+func TestWaitForDNSPropagation(t *testing.T) {
+	server := newFakeNameServer(t)
+	p := testPropagationProvider(server)
+
+	records := []libdns.Record{
+		libdns.TXT{Name: "_acme-challenge", Text: "token-1"},
+		libdns.Address{Name: "www", IP: netip.MustParseAddr("192.0.2.1")},
+	}
+
+	// serve the records only after a few polling rounds
+	server.set("_acme-challenge.example.com.", dnsmessage.TypeTXT, &dnsmessage.TXTResource{TXT: []string{"other"}})
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		server.set("_acme-challenge.example.com.", dnsmessage.TypeTXT,
+			&dnsmessage.TXTResource{TXT: []string{"other"}},
+			&dnsmessage.TXTResource{TXT: []string{"token-1"}})
+		server.set("www.example.com.", dnsmessage.TypeA, &dnsmessage.AResource{A: [4]byte{192, 0, 2, 1}})
+	}()
+
+	if err := p.WaitForDNSPropagation(context.Background(), "example.com.", records); err != nil {
+		t.Fatalf("expected records to propagate, got %v", err)
+	}
+
+	// the records are still served, so waiting for their removal times out
+	p.DNSPropagationTimeout = 50 * time.Millisecond
+	err := p.WaitForDNSRemoval(context.Background(), "example.com.", records)
+	if !errors.Is(err, ErrDNSPropagationTimeout) {
+		t.Fatalf("expected ErrDNSPropagationTimeout, got %v", err)
+	}
+
+	server.set("_acme-challenge.example.com.", dnsmessage.TypeTXT, &dnsmessage.TXTResource{TXT: []string{"other"}})
+	server.set("www.example.com.", dnsmessage.TypeA)
+	if err = p.WaitForDNSRemoval(context.Background(), "example.com.", records); err != nil {
+		t.Fatalf("expected records to be removed, got %v", err)
+	}
+}
+
+func TestNormalizeRecordData(t *testing.T) {
+	cases := []struct {
+		recordType string
+		input      string
+		expected   string
+		ok         bool
+	}{
+		{recordType: "A", input: "192.0.2.1", expected: "192.0.2.1", ok: true},
+		{recordType: "AAAA", input: "2001:DB8::0001", expected: "2001:db8::1", ok: true},
+		{recordType: "CNAME", input: "Target.Example.com", expected: "target.example.com.", ok: true},
+		{recordType: "MX", input: "10 Mail.example.com.", expected: "10 mail.example.com.", ok: true},
+		{recordType: "SRV", input: "1 2 443 host.example.com", expected: "1 2 443 host.example.com.", ok: true},
+		{recordType: "CAA", input: `0 ISSUE "letsencrypt.org"`, expected: `0 issue "letsencrypt.org"`, ok: true},
+		{recordType: "TXT", input: "Case Sensitive", expected: "Case Sensitive", ok: true},
+		{recordType: "HTTPS", input: `1 . alpn="h2"`, ok: false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.recordType, func(t *testing.T) {
+			actual, ok := normalizeRecordData(c.recordType, c.input)
+			if ok != c.ok || actual != c.expected {
+				t.Errorf("expected (%q, %v), got (%q, %v)", c.expected, c.ok, actual, ok)
+			}
+		})
+	}
+}
+
+func TestFormatCAA(t *testing.T) {
+	data := append([]byte{0, 5}, []byte("issueletsencrypt.org")...)
+	actual, ok := formatCAA(data)
+	if !ok || actual != `0 issue "letsencrypt.org"` {
+		t.Errorf("unexpected CAA formatting: %q, %v", actual, ok)
+	}
+}
@@ -1,13 +1,20 @@
 package route53
 
 import (
+	"cmp"
 	"context"
+	"errors"
 	"log/slog"
+	"maps"
+	"slices"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	r53 "github.com/aws/aws-sdk-go-v2/service/route53"
 	"github.com/libdns/libdns"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Provider implements the libdns interfaces for Route53.
@@ -45,6 +52,23 @@ type Provider struct {
 	// to be propagated within AWS infrastructure. Default is 1 minute.
 	Route53MaxWait time.Duration `json:"route53_max_wait,omitempty"`
 
+	// Route53SyncMinDelay is the minimum delay between two polls of the
+	// change status while waiting for Route53 synchronization. The delay
+	// grows exponentially, with jitter, up to Route53SyncMaxDelay. Default is
+	// 30 seconds.
+	Route53SyncMinDelay time.Duration `json:"route53_sync_min_delay,omitempty"`
+
+	// Route53SyncMaxDelay is the maximum delay between two polls of the
+	// change status. Default is 2 minutes; it is raised to
+	// Route53SyncMinDelay if lower.
+	Route53SyncMaxDelay time.Duration `json:"route53_sync_max_delay,omitempty"`
+
+	// Route53SyncJitter is the upper bound of a random delay added before
+	// the first poll of the change status. It spreads out the polls of
+	// changes submitted together, so bulk operations do not exhaust the
+	// Route53 API quota. Default is no jitter.
+	Route53SyncJitter time.Duration `json:"route53_sync_jitter,omitempty"`
+
 	// WaitForRoute53Sync if set to true, it will wait for the record to be
 	// propagated within AWS infrastructure before returning. This is not related
 	// to DNS propagation, that could take much longer.
@@ -55,6 +79,79 @@ type Provider struct {
 	// This can speed up bulk delete operations where waiting is not necessary.
 	SkipRoute53SyncOnDelete bool `json:"skip_route53_sync_on_delete,omitempty"`
 
+	// Route53SyncPolicy refines WaitForRoute53Sync and SkipRoute53SyncOnDelete
+	// per operation and per record type. See SyncPolicy.
+	Route53SyncPolicy SyncPolicy `json:"route53_sync_policy,omitzero"`
+
+	// Route53SyncOncePerCall if set to true, each call submits the changes
+	// for all its record sets first and then waits for the ones the sync
+	// settings require concurrently, instead of waiting for each record set
+	// before submitting the next one. A call touching N record sets then
+	// takes about one sync delay instead of N.
+	Route53SyncOncePerCall bool `json:"route53_sync_once_per_call,omitempty"`
+
+	// MaxConcurrentRecordSets is the number of record sets AppendRecords,
+	// DeleteRecords and SetRecords process at the same time when a call
+	// touches several (name, type) pairs. The returned records are in the
+	// same order whatever the concurrency, and the errors of all the failed
+	// record sets are returned together, along with the records of those
+	// that succeeded. Default is 1, one record set after the other. Route53
+	// allows 5 API requests per second per account; throttled requests are
+	// retried.
+	MaxConcurrentRecordSets int `json:"max_concurrent_record_sets,omitempty"`
+
+	// VerifyDNSPropagation if set to true, it will query the zone's
+	// authoritative name servers directly after records are appended, set or
+	// deleted, until the new values are served (or the deleted ones are gone)
+	// everywhere. Unlike WaitForRoute53Sync, this checks what DNS clients
+	// actually see.
+	VerifyDNSPropagation bool `json:"verify_dns_propagation,omitempty"`
+
+	// DNSPropagationResolvers are the name servers queried by the DNS
+	// propagation check, as "host" or "host:port". If not set, the name
+	// servers of the zone's apex NS record set are used.
+	DNSPropagationResolvers []string `json:"dns_propagation_resolvers,omitempty"`
+
+	// DNSPropagationTimeout is the maximum amount of time to wait for DNS
+	// propagation. Default is 2 minutes.
+	DNSPropagationTimeout time.Duration `json:"dns_propagation_timeout,omitempty"`
+
+	// DNSPropagationInterval is the delay between two rounds of DNS
+	// propagation queries. Default is 5 seconds.
+	DNSPropagationInterval time.Duration `json:"dns_propagation_interval,omitempty"`
+
+	// Journal, if set, receives an entry for every change submitted to
+	// Route53, with the values of the changed record sets before and after
+	// the change. See Journal.
+	Journal Journal `json:"-"`
+
+	// JournalFile, if set and Journal is not, makes the provider append
+	// journal entries to this file as JSON lines. See FileJournal.
+	JournalFile string `json:"journal_file,omitempty"`
+
+	// SetLockIdleTimeout, if set, evicts the in-process lock of a record set
+	// once no caller has used it for this long, so long-running processes
+	// managing many short-lived names do not accumulate them. By default the
+	// locks are kept for the lifetime of the provider.
+	SetLockIdleTimeout time.Duration `json:"set_lock_idle_timeout,omitempty"`
+
+	// RecordCacheTTL, if set, caches the record sets of each hosted zone for
+	// this long after listing them. GetRecords and the existing values read
+	// by AppendRecords, DeleteRecords and SetRecords then come from the
+	// cache, which the provider updates with its own changes and drops when
+	// a change fails. Changes made by other processes or in the AWS console
+	// are only seen once the cache expires. The other methods, such as
+	// Snapshot or ImportZone, always list the zone. Default is no cache.
+	RecordCacheTTL time.Duration `json:"record_cache_ttl,omitempty"`
+
+	// AliasRecords makes the record methods handle Route53 alias record
+	// sets as Alias records: GetRecords returns them, and AppendRecords,
+	// DeleteRecords and SetRecords accept them. By default, alias record
+	// sets are left out of the records the record methods read, and Alias
+	// records are rejected. ExportZone, Snapshot and the other zone methods
+	// handle alias record sets either way.
+	AliasRecords bool `json:"alias_records,omitempty"`
+
 	// HostedZoneID is the ID of the hosted zone to use. If not set, it will
 	// be discovered from the zone name.
 	//
@@ -68,19 +165,48 @@ type Provider struct {
 	// go.uber.org/zap/exp/zapslog.
 	//
 	// All events are emitted at Debug level except for ambiguous zone
-	// resolution, which is Warn.
+	// resolution, journal and metric setup failures, which are Warn. At
+	// Debug level, every attempt of a Route53 API call is logged, including
+	// the retries of throttled calls, with its error code, the delay before
+	// the retry and the request ID; credentials in the request headers are
+	// redacted.
 	Logger *slog.Logger `json:"-"`
 
+	// TracerProvider, if set, receives OpenTelemetry spans for every public
+	// method and every Route53 API call, including the polls of the change
+	// status, with the zone, record names and types and change IDs as
+	// attributes.
+	TracerProvider trace.TracerProvider `json:"-"`
+
+	// MeterProvider, if set, receives OpenTelemetry metrics: counts of
+	// Route53 API calls, retries and throttling errors, API call durations
+	// and the time spent waiting for Route53 synchronization.
+	MeterProvider metric.MeterProvider `json:"-"`
+
+	// Metrics, if set, receives counts of changes, API call and sync wait
+	// durations and the number of callers waiting for a record set. See
+	// Metrics.
+	Metrics Metrics `json:"-"`
+
+	telemetry   telemetry
+	recordCache recordCache
+
 	initOnce sync.Once
 	// setLocks serializes read-modify-write critical sections per
 	// (zoneID, name, recordType). Distinct keys parallelize; concurrent
 	// callers touching the same key serialize. See lockSet.
-	setLocks sync.Map
+	setLocks      map[setLockKey]*setLock
+	setLocksMu    sync.Mutex
+	setLocksSwept time.Time
 }
 
-// GetRecords lists all the records in the zone.
-func (p *Provider) GetRecords(ctx context.Context, zone string) ([]libdns.Record, error) {
-	p.init(ctx)
+// GetRecords lists all the records in the zone, sorted by name in DNSSEC
+// canonical order (RFC 4034, section 6.1) and then by type, with SOA and NS
+// first. The values of a record set keep the order Route53 returns them in.
+func (p *Provider) GetRecords(ctx context.Context, zone string) (_ []libdns.Record, err error) {
+	ctx, end := p.startSpan(ctx, "GetRecords", zoneAttributes(zone, nil))
+	defer end(&err)
+	ctx = p.withRecordCache(ctx)
 
 	zoneID, err := p.getZoneID(ctx, zone)
 	if err != nil {
@@ -91,13 +217,28 @@ func (p *Provider) GetRecords(ctx context.Context, zone string) ([]libdns.Record
 	if err != nil {
 		return nil, err
 	}
+	records = slices.DeleteFunc(records, p.hidesAlias)
 
+	slices.SortStableFunc(records, compareZoneFileRecords)
 	return records, nil
 }
 
-// AppendRecords adds records to the zone. It returns the records that were added.
-func (p *Provider) AppendRecords(ctx context.Context, zone string, records []libdns.Record) ([]libdns.Record, error) {
-	p.init(ctx)
+// AppendRecords adds records to the zone. It returns the records that were
+// added, sorted by name and type like those of GetRecords.
+func (p *Provider) AppendRecords(
+	ctx context.Context,
+	zone string,
+	records []libdns.Record,
+) (_ []libdns.Record, err error) {
+	ctx, end := p.startSpan(ctx, "AppendRecords", zoneAttributes(zone, records))
+	defer end(&err)
+
+	ctx = withOperation(ctx, OperationAppend)
+	ctx = p.withRecordCache(ctx)
+
+	if err = p.checkAliasRecords(records); err != nil {
+		return nil, err
+	}
 
 	zoneID, err := p.getZoneID(ctx, zone)
 	if err != nil {
@@ -107,15 +248,25 @@ func (p *Provider) AppendRecords(ctx context.Context, zone string, records []lib
 	// group records by name+type since Route53 treats them as a single ResourceRecordSet
 	recordSets := p.groupRecordsByKey(records)
 
-	var createdRecords []libdns.Record
+	ctx, waitDeferred := p.deferSync(ctx)
 
 	// process each record set
-	for key, recordGroup := range recordSets {
-		created, appendErr := p.appendRecordSet(ctx, zoneID, zone, key, recordGroup)
-		if appendErr != nil {
-			return nil, appendErr
+	createdRecords, err := p.forEachRecordSet(ctx, recordSets,
+		func(ctx context.Context, key recordSetKey, recordGroup []libdns.Record) ([]libdns.Record, error) {
+			return p.appendRecordSet(ctx, zoneID, zone, key, recordGroup)
+		})
+	if err != nil {
+		return createdRecords, err
+	}
+
+	if err = waitDeferred(); err != nil {
+		return nil, err
+	}
+
+	if p.VerifyDNSPropagation {
+		if err = p.waitForDNS(ctx, zone, createdRecords, true); err != nil {
+			return nil, err
 		}
-		createdRecords = append(createdRecords, created...)
 	}
 
 	return createdRecords, nil
@@ -134,7 +285,10 @@ func (p *Provider) appendRecordSet(
 
 	// Serialize the read-merge-UPSERT cycle for this (zone, name, type)
 	// against any other goroutine doing the same. See lockSet.
-	unlock := p.lockSet(setLockKey{zoneID: zoneID, name: key.name, recordType: key.recordType})
+	unlock, err := p.lockSet(ctx, setLockKey{zoneID: zoneID, name: key.name, recordType: key.recordType})
+	if err != nil {
+		return nil, err
+	}
 	defer unlock()
 
 	// Retrieve existing records so we can merge and UPSERT.
@@ -142,29 +296,18 @@ func (p *Provider) appendRecordSet(
 	// entity — we must include all existing values when updating it. Using CREATE
 	// would fail if the record set already exists (e.g. a stale ACME challenge
 	// TXT record from a previous attempt).
-	existingRecords, err := p.getRecords(ctx, zoneID, zone)
+	existingValues, err := p.getRecordSet(ctx, zoneID, zone, key)
 	if err != nil {
 		return nil, err
 	}
 
-	// find existing records for this name+type. getRecords returns relative
-	// names, and key.name is also relative (set by groupRecordsByKey from
-	// the caller's input record), so we compare directly.
-	var existingValues []libdns.Record
-	for _, existing := range existingRecords {
-		existingRR := existing.RR()
-		if existingRR.Name == key.name && existingRR.Type == key.recordType {
-			existingValues = append(existingValues, existing)
-		}
-	}
-
 	// combine existing records with new ones
 	allRecords := make([]libdns.Record, 0, len(existingValues)+len(recordGroup))
 	allRecords = append(allRecords, existingValues...)
 	allRecords = append(allRecords, recordGroup...)
 
 	// use UPSERT to set all values at once
-	err = p.setRecordSet(ctx, zoneID, zone, key.name, key.recordType, allRecords)
+	err = p.setRecordSet(ctx, zoneID, zone, key.name, key.recordType, existingValues, allRecords)
 	if err != nil {
 		return nil, err
 	}
@@ -173,6 +316,34 @@ func (p *Provider) appendRecordSet(
 	return recordGroup, nil
 }
 
+// getRecordSet returns the current values of a single ResourceRecordSet.
+// Callers that modify the set should hold its per-tuple lock.
+func (p *Provider) getRecordSet(
+	ctx context.Context,
+	zoneID, zone string,
+	key recordSetKey,
+) ([]libdns.Record, error) {
+	existingRecords, err := p.getRecords(ctx, zoneID, zone)
+	if err != nil {
+		return nil, err
+	}
+
+	// find existing records for this name+type. getRecords returns relative
+	// names, and key.name is also relative (set by groupRecordsByKey from
+	// the caller's input record), so we compare directly.
+	var existingValues []libdns.Record
+	for _, existing := range existingRecords {
+		if p.hidesAlias(existing) {
+			continue
+		}
+		existingRR := existing.RR()
+		if existingRR.Name == key.name && existingRR.Type == key.recordType {
+			existingValues = append(existingValues, existing)
+		}
+	}
+	return existingValues, nil
+}
+
 // recordSetKey uniquely identifies a Route53 ResourceRecordSet by name and type.
 type recordSetKey struct {
 	name       string
@@ -180,12 +351,22 @@ type recordSetKey struct {
 }
 
 // DeleteRecords deletes the records from the zone. If a record does not have an ID,
-// it will be looked up. It returns the records that were deleted.
-func (p *Provider) DeleteRecords(ctx context.Context, zone string, records []libdns.Record) ([]libdns.Record, error) {
-	p.init(ctx)
+// it will be looked up. It returns the records that were deleted, sorted by
+// name and type like those of GetRecords.
+func (p *Provider) DeleteRecords(
+	ctx context.Context,
+	zone string,
+	records []libdns.Record,
+) (_ []libdns.Record, err error) {
+	ctx, end := p.startSpan(ctx, "DeleteRecords", zoneAttributes(zone, records))
+	defer end(&err)
 
-	// mark this context as a delete operation
-	ctx = context.WithValue(ctx, contextKeyIsDeleteOperation, true)
+	ctx = withOperation(ctx, OperationDelete)
+	ctx = p.withRecordCache(ctx)
+
+	if err = p.checkAliasRecords(records); err != nil {
+		return nil, err
+	}
 
 	zoneID, err := p.getZoneID(ctx, zone)
 	if err != nil {
@@ -197,19 +378,33 @@ func (p *Provider) DeleteRecords(ctx context.Context, zone string, records []lib
 	// callers cannot observe stale state.
 	toDelete := p.groupRecordsByKey(records)
 
-	var deletedRecords []libdns.Record
-	for key, deleteGroup := range toDelete {
-		deleted, deleteErr := p.processRecordSetDeletion(ctx, zoneID, zone, key, deleteGroup)
-		if deleteErr != nil {
-			return nil, deleteErr
+	ctx, waitDeferred := p.deferSync(ctx)
+
+	deletedRecords, err := p.forEachRecordSet(ctx, toDelete, func(
+		ctx context.Context, key recordSetKey, deleteGroup []libdns.Record,
+	) ([]libdns.Record, error) {
+		return p.processRecordSetDeletion(ctx, zoneID, zone, key, deleteGroup)
+	})
+	if err != nil {
+		return deletedRecords, err
+	}
+
+	if err = waitDeferred(); err != nil {
+		return nil, err
+	}
+
+	if p.VerifyDNSPropagation {
+		if err = p.waitForDNS(ctx, zone, deletedRecords, false); err != nil {
+			return nil, err
 		}
-		deletedRecords = append(deletedRecords, deleted...)
 	}
 
 	return deletedRecords, nil
 }
 
-// groupRecordsByKey groups records by their name and type.
+// groupRecordsByKey groups records by their name and type, keeping their
+// order within each group. Iterate over the groups with sortedRecordSetKeys,
+// so the API calls are made in the same order from one run to the next.
 func (p *Provider) groupRecordsByKey(records []libdns.Record) map[recordSetKey][]libdns.Record {
 	grouped := make(map[recordSetKey][]libdns.Record)
 	for _, record := range records {
@@ -223,6 +418,63 @@ func (p *Provider) groupRecordsByKey(records []libdns.Record) map[recordSetKey][
 	return grouped
 }
 
+// forEachRecordSet calls fn for every record set of grouped, up to
+// MaxConcurrentRecordSets at a time, and returns the records fn returned in
+// the order of the record sets by name and type, whatever order they
+// completed in.
+//
+// Once a record set fails, the ones not started yet are skipped; those in
+// flight are left to complete, as abandoning a submitted change would leave
+// its outcome unknown. The records of the record sets that succeeded are
+// returned along with the errors of all the failed ones, joined.
+func (p *Provider) forEachRecordSet(
+	ctx context.Context,
+	grouped map[recordSetKey][]libdns.Record,
+	fn func(ctx context.Context, key recordSetKey, group []libdns.Record) ([]libdns.Record, error),
+) ([]libdns.Record, error) {
+	keys := sortedRecordSetKeys(grouped)
+	results := make([][]libdns.Record, len(keys))
+	errs := make([]error, len(keys))
+
+	var next atomic.Int64
+	var failed atomic.Bool
+	var wg sync.WaitGroup
+	for range min(max(p.MaxConcurrentRecordSets, 1), len(keys)) {
+		wg.Go(func() {
+			for !failed.Load() {
+				i := int(next.Add(1)) - 1
+				if i >= len(keys) {
+					return
+				}
+				results[i], errs[i] = fn(ctx, keys[i], grouped[keys[i]])
+				if errs[i] != nil {
+					failed.Store(true)
+				}
+			}
+		})
+	}
+	wg.Wait()
+
+	var records []libdns.Record
+	for i, result := range results {
+		if errs[i] == nil {
+			records = append(records, result...)
+		}
+	}
+	return records, errors.Join(errs...)
+}
+
+// sortedRecordSetKeys returns the keys of grouped by name, in DNSSEC
+// canonical order, and then by type, with SOA and NS first.
+func sortedRecordSetKeys[V any](grouped map[recordSetKey]V) []recordSetKey {
+	return slices.SortedFunc(maps.Keys(grouped), func(a, b recordSetKey) int {
+		if c := compareNames(a.name, b.name); c != 0 {
+			return c
+		}
+		return cmp.Compare(typeRank(a.recordType), typeRank(b.recordType))
+	})
+}
+
 // processRecordSetDeletion handles the deletion of records from a single
 // ResourceRecordSet. The existing values are read inside the per-tuple lock
 // so concurrent callers cannot race the read-modify-write cycle.
@@ -232,22 +484,17 @@ func (p *Provider) processRecordSetDeletion(
 	key recordSetKey,
 	deleteGroup []libdns.Record,
 ) ([]libdns.Record, error) {
-	unlock := p.lockSet(setLockKey{zoneID: zoneID, name: key.name, recordType: key.recordType})
+	unlock, err := p.lockSet(ctx, setLockKey{zoneID: zoneID, name: key.name, recordType: key.recordType})
+	if err != nil {
+		return nil, err
+	}
 	defer unlock()
 
-	// fetch current state of this record set under the lock. getRecords
-	// returns relative names, matching key.name's format.
-	existingRecords, err := p.getRecords(ctx, zoneID, zone)
+	// fetch current state of this record set under the lock
+	existingValues, err := p.getRecordSet(ctx, zoneID, zone, key)
 	if err != nil {
 		return nil, err
 	}
-	var existingValues []libdns.Record
-	for _, existing := range existingRecords {
-		existingRR := existing.RR()
-		if existingRR.Name == key.name && existingRR.Type == key.recordType {
-			existingValues = append(existingValues, existing)
-		}
-	}
 	if len(existingValues) == 0 {
 		return nil, nil
 	}
@@ -276,7 +523,8 @@ func (p *Provider) processRecordSetDeletion(
 		}
 	} else {
 		// update the record set with remaining values
-		if setErr := p.setRecordSet(ctx, zoneID, zone, key.name, key.recordType, remainingValues); setErr != nil {
+		setErr := p.setRecordSet(ctx, zoneID, zone, key.name, key.recordType, existingValues, remainingValues)
+		if setErr != nil {
 			return nil, setErr
 		}
 	}
@@ -287,12 +535,25 @@ func (p *Provider) processRecordSetDeletion(
 // SetRecords sets the records in the zone. For each (name, type) tuple
 // represented in the input, the corresponding ResourceRecordSet is replaced
 // with exactly the values provided — other tuples in the zone are not
-// touched. It returns the records that were set.
+// touched. It returns the records that were set, sorted by name
+// and type like those of GetRecords.
 //
 // Multiple input records sharing the same (name, type) are combined into a
 // single UPSERT carrying all their values, matching libdns semantics.
-func (p *Provider) SetRecords(ctx context.Context, zone string, records []libdns.Record) ([]libdns.Record, error) {
-	p.init(ctx)
+func (p *Provider) SetRecords(
+	ctx context.Context,
+	zone string,
+	records []libdns.Record,
+) (_ []libdns.Record, err error) {
+	ctx, end := p.startSpan(ctx, "SetRecords", zoneAttributes(zone, records))
+	defer end(&err)
+
+	ctx = withOperation(ctx, OperationSet)
+	ctx = p.withRecordCache(ctx)
+
+	if err = p.checkAliasRecords(records); err != nil {
+		return nil, err
+	}
 
 	zoneID, err := p.getZoneID(ctx, zone)
 	if err != nil {
@@ -303,12 +564,27 @@ func (p *Provider) SetRecords(ctx context.Context, zone string, records []libdns
 	// UPSERT — otherwise a per-record loop would last-write-wins each one.
 	grouped := p.groupRecordsByKey(records)
 
-	var updatedRecords []libdns.Record
-	for key, group := range grouped {
-		if setErr := p.setRecordSetLocked(ctx, zoneID, zone, key, group); setErr != nil {
-			return nil, setErr
+	ctx, waitDeferred := p.deferSync(ctx)
+
+	updatedRecords, err := p.forEachRecordSet(ctx, grouped,
+		func(ctx context.Context, key recordSetKey, group []libdns.Record) ([]libdns.Record, error) {
+			if setErr := p.setRecordSetLocked(ctx, zoneID, zone, key, group); setErr != nil {
+				return nil, setErr
+			}
+			return group, nil
+		})
+	if err != nil {
+		return updatedRecords, err
+	}
+
+	if err = waitDeferred(); err != nil {
+		return nil, err
+	}
+
+	if p.VerifyDNSPropagation {
+		if err = p.waitForDNS(ctx, zone, updatedRecords, true); err != nil {
+			return nil, err
 		}
-		updatedRecords = append(updatedRecords, group...)
 	}
 
 	return updatedRecords, nil
@@ -322,9 +598,20 @@ func (p *Provider) setRecordSetLocked(
 	key recordSetKey,
 	group []libdns.Record,
 ) error {
-	unlock := p.lockSet(setLockKey{zoneID: zoneID, name: key.name, recordType: key.recordType})
+	unlock, err := p.lockSet(ctx, setLockKey{zoneID: zoneID, name: key.name, recordType: key.recordType})
+	if err != nil {
+		return err
+	}
 	defer unlock()
-	return p.setRecordSet(ctx, zoneID, zone, key.name, key.recordType, group)
+
+	// The previous values are only needed to report or revert the change.
+	var before []libdns.Record
+	if p.needsBefore(ctx) {
+		if before, err = p.getRecordSet(ctx, zoneID, zone, key); err != nil {
+			return err
+		}
+	}
+	return p.setRecordSet(ctx, zoneID, zone, key.name, key.recordType, before, group)
 }
 
 // Interface guards.
@@ -333,4 +620,5 @@ var (
 	_ libdns.RecordAppender = (*Provider)(nil)
 	_ libdns.RecordSetter   = (*Provider)(nil)
 	_ libdns.RecordDeleter  = (*Provider)(nil)
+	_ libdns.ZoneLister     = (*Provider)(nil)
 )
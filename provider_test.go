@@ -0,0 +1,207 @@
+package route53 //nolint:testpackage // Testing internal functions
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+// listRecordSetsResponse is a ListResourceRecordSets response holding sets,
+// each a ResourceRecordSet element.
+func listRecordSetsResponse(sets ...string) string {
+	return `<ListResourceRecordSetsResponse xmlns="https://route53.amazonaws.com/doc/2013-04-01/">` +
+		"<ResourceRecordSets>" + strings.Join(sets, "") + "</ResourceRecordSets>" +
+		"<IsTruncated>false</IsTruncated><MaxItems>300</MaxItems></ListResourceRecordSetsResponse>"
+}
+
+// recordSetXML is a ResourceRecordSet element with the given values.
+func recordSetXML(name, recordType string, values ...string) string {
+	records := ""
+	for _, value := range values {
+		records += "<ResourceRecord><Value>" + value + "</Value></ResourceRecord>"
+	}
+	return "<ResourceRecordSet><Name>" + name + "</Name><Type>" + recordType + "</Type>" +
+		"<TTL>300</TTL><ResourceRecords>" + records + "</ResourceRecords></ResourceRecordSet>"
+}
+
+func TestGetRecordsSorted(t *testing.T) {
+	provider := &Provider{HostedZoneID: "Z1"}
+	newCannedProvider(t, provider, cannedResponse{status: http.StatusOK, body: listRecordSetsResponse(
+		recordSetXML("www.example.com.", "TXT", `"b"`, `"a"`),
+		recordSetXML("a.example.com.", "A", "192.0.2.1"),
+		recordSetXML("www.example.com.", "A", "192.0.2.2"),
+		recordSetXML("example.com.", "NS", "ns1.example.net."),
+		recordSetXML("example.com.", "SOA", "ns1.example.net. admin.example.com. 1 7200 900 1209600 86400"),
+	)})
+
+	records, err := provider.GetRecords(context.Background(), "example.com.")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var got []string
+	for _, record := range records {
+		rr := record.RR()
+		got = append(got, rr.Name+" "+rr.Type+" "+rr.Data)
+	}
+	expected := []string{
+		"@ SOA ns1.example.net. admin.example.com. 1 7200 900 1209600 86400",
+		"@ NS ns1.example.net.",
+		"a A 192.0.2.1",
+		"www A 192.0.2.2",
+		"www TXT b",
+		"www TXT a",
+	}
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+	}
+}
+
+func TestGetRecordsAlias(t *testing.T) {
+	listing := listRecordSetsResponse(
+		recordSetXML("www.example.com.", "A", "192.0.2.1"),
+		"<ResourceRecordSet><Name>cdn.example.com.</Name><Type>A</Type><AliasTarget>"+
+			"<HostedZoneId>Z2FDTNDATAQYW2</HostedZoneId><DNSName>d111111abcdef8.cloudfront.net.</DNSName>"+
+			"<EvaluateTargetHealth>false</EvaluateTargetHealth></AliasTarget></ResourceRecordSet>",
+	)
+	alias := Alias{Name: "cdn", Type: "A", Target: "d111111abcdef8.cloudfront.net.", HostedZoneID: "Z2FDTNDATAQYW2"}
+
+	for _, enabled := range []bool{false, true} {
+		provider := &Provider{HostedZoneID: "Z1", AliasRecords: enabled}
+		newCannedProvider(t, provider, cannedResponse{status: http.StatusOK, body: listing})
+
+		records, err := provider.GetRecords(context.Background(), "example.com.")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		var aliases []Alias
+		for _, record := range records {
+			if a, ok := record.(Alias); ok {
+				aliases = append(aliases, a)
+			}
+		}
+		switch {
+		case !enabled && (len(records) != 1 || len(aliases) != 0):
+			t.Errorf("expected the alias left out, got %v", records)
+		case enabled && (len(records) != 2 || len(aliases) != 1 || aliases[0] != alias):
+			t.Errorf("expected the alias returned, got %v", records)
+		}
+	}
+
+	provider := &Provider{HostedZoneID: "Z1"}
+	if _, err := provider.SetRecords(context.Background(), "example.com.", []libdns.Record{alias}); err == nil {
+		t.Error("expected alias records rejected without AliasRecords")
+	}
+}
+
+func TestForEachRecordSet(t *testing.T) {
+	provider := &Provider{MaxConcurrentRecordSets: 3}
+	grouped := provider.groupRecordsByKey([]libdns.Record{
+		libdns.TXT{Name: "c", Text: "1"},
+		libdns.TXT{Name: "a", Text: "1"},
+		libdns.Address{Name: "b"},
+		libdns.TXT{Name: "a", Text: "2"},
+		libdns.TXT{Name: "d", Text: "1"},
+		libdns.TXT{Name: "@", Text: "1"},
+	})
+
+	var running, peak atomic.Int32
+	records, err := provider.forEachRecordSet(context.Background(), grouped,
+		func(_ context.Context, _ recordSetKey, group []libdns.Record) ([]libdns.Record, error) {
+			n := running.Add(1)
+			defer running.Add(-1)
+			for {
+				current := peak.Load()
+				if n <= current || peak.CompareAndSwap(current, n) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+			return group, nil
+		})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var names []string
+	for _, record := range records {
+		names = append(names, record.RR().Name+"/"+record.RR().Data)
+	}
+	expected := []string{"@/1", "a/1", "a/2", "b/", "c/1", "d/1"}
+	if len(names) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, names)
+	}
+	for i := range expected {
+		if names[i] != expected[i] {
+			t.Fatalf("expected %v, got %v", expected, names)
+		}
+	}
+	if peak.Load() < 2 || peak.Load() > 3 {
+		t.Errorf("expected up to 3 record sets at a time, got %d", peak.Load())
+	}
+}
+
+func TestForEachRecordSetErrors(t *testing.T) {
+	grouped := (&Provider{}).groupRecordsByKey([]libdns.Record{
+		libdns.TXT{Name: "a", Text: "1"},
+		libdns.TXT{Name: "b", Text: "1"},
+		libdns.TXT{Name: "c", Text: "1"},
+	})
+	errA, errB, errC := errors.New("a failed"), errors.New("b failed"), errors.New("c failed")
+	failures := map[string]error{"a": errA, "b": errB}
+
+	// concurrently, every record set in flight completes and all the errors
+	// are returned
+	var calls atomic.Int32
+	var started sync.WaitGroup
+	started.Add(2)
+	_, err := (&Provider{MaxConcurrentRecordSets: 2}).forEachRecordSet(context.Background(), grouped,
+		func(_ context.Context, key recordSetKey, group []libdns.Record) ([]libdns.Record, error) {
+			calls.Add(1)
+			started.Done()
+			started.Wait()
+			return group, failures[key.name]
+		})
+	if !errors.Is(err, errA) || !errors.Is(err, errB) {
+		t.Errorf("expected both errors, got %v", err)
+	}
+	if calls.Load() != 2 {
+		t.Errorf("expected the record set after the failures skipped, got %d calls", calls.Load())
+	}
+
+	// sequentially, the first failure stops the call
+	calls.Store(0)
+	_, err = (&Provider{}).forEachRecordSet(context.Background(), grouped,
+		func(_ context.Context, key recordSetKey, group []libdns.Record) ([]libdns.Record, error) {
+			calls.Add(1)
+			return group, failures[key.name]
+		})
+	if !errors.Is(err, errA) || errors.Is(err, errB) || calls.Load() != 1 {
+		t.Errorf("expected to stop at the first error, got %v after %d calls", err, calls.Load())
+	}
+
+	// the records of the record sets that succeeded are returned with the
+	// errors
+	records, err := (&Provider{}).forEachRecordSet(context.Background(), grouped,
+		func(_ context.Context, key recordSetKey, group []libdns.Record) ([]libdns.Record, error) {
+			if key.name == "c" {
+				return group, errC
+			}
+			return group, nil
+		})
+	var names []string
+	for _, record := range records {
+		names = append(names, record.RR().Name)
+	}
+	if !errors.Is(err, errC) || !slices.Equal(names, []string{"a", "b"}) {
+		t.Errorf("expected a and b along with the error, got %v and %v", names, err)
+	}
+}
@@ -70,3 +70,48 @@ func unquote(s string) string {
 
 	return strings.Trim(sb.String(), `"`)
 }
+
+// quoteZoneFile formats TXT data as the character-strings of an RFC 1035
+// master file: split into strings of at most 255 bytes, each quoted, with
+// quotes and backslashes escaped and non-printable bytes written as decimal
+// \DDD escapes. Unlike quote, which produces the octal escapes Route53
+// expects, the result is read back correctly by BIND and other tools.
+func quoteZoneFile(s string) string {
+	if s == "" {
+		return `""`
+	}
+
+	chunks := chunkString(s, maxTXTValueLength)
+	for i, chunk := range chunks {
+		chunks[i] = `"` + escapeZoneFile(chunk, `"\`) + `"`
+	}
+	return strings.Join(chunks, " ")
+}
+
+// zoneFileName converts a record name as returned by Route53, where special
+// characters such as the wildcard are octal escapes, into master file form.
+func zoneFileName(name string) string {
+	if name == "@" {
+		return name
+	}
+	return escapeZoneFile(unquote(name), "\"\\();$ ")
+}
+
+// escapeZoneFile escapes the bytes of s that are not printable ASCII, or
+// that are in special, as RFC 1035 escapes.
+func escapeZoneFile(s, special string) string {
+	var sb strings.Builder
+	for i := range len(s) {
+		c := s[i]
+		switch {
+		case c < 32 || c >= 127:
+			_, _ = fmt.Fprintf(&sb, "\\%03d", c)
+		case strings.IndexByte(special, c) >= 0:
+			sb.WriteByte('\\')
+			sb.WriteByte(c)
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return sb.String()
+}
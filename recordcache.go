@@ -0,0 +1,160 @@
+package route53
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/route53/types"
+)
+
+// recordCache holds the record sets of hosted zones for RecordCacheTTL, see
+// Provider.RecordCacheTTL.
+type recordCache struct {
+	mu    sync.Mutex
+	zones map[string]*cachedZone
+	// versions counts the updates of each hosted zone, so a listing that
+	// raced with a change is not cached.
+	versions map[string]uint64
+}
+
+// cachedZone is the cached content of a hosted zone.
+type cachedZone struct {
+	sets    []types.ResourceRecordSet
+	expires time.Time
+}
+
+// withRecordCache marks ctx as that of a record method, such as GetRecords
+// or SetRecords, whose listings may come from the RecordCacheTTL cache. The
+// other methods, such as Snapshot or ImportZone, always list the zone.
+func (p *Provider) withRecordCache(ctx context.Context) context.Context {
+	if p.RecordCacheTTL <= 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, contextKeyRecordCache, true)
+}
+
+// usesRecordCache reports whether the listings of ctx may come from the
+// RecordCacheTTL cache.
+func usesRecordCache(ctx context.Context) bool {
+	cached, _ := ctx.Value(contextKeyRecordCache).(bool)
+	return cached
+}
+
+// get returns the cached record sets of a hosted zone, if fresh, and the
+// version of the zone to pass to put after listing it otherwise.
+func (c *recordCache) get(zoneID string) ([]types.ResourceRecordSet, uint64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := cacheKey(zoneID)
+	zone, ok := c.zones[key]
+	if !ok || time.Now().After(zone.expires) {
+		return nil, c.versions[key], false
+	}
+	return slices.Clone(zone.sets), 0, true
+}
+
+// put caches the record sets of a hosted zone listed at version, unless the
+// zone was updated since.
+func (c *recordCache) put(zoneID string, version uint64, sets []types.ResourceRecordSet, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := cacheKey(zoneID)
+	if c.versions[key] != version {
+		return
+	}
+	if c.zones == nil {
+		c.zones = make(map[string]*cachedZone)
+	}
+	c.zones[key] = &cachedZone{sets: slices.Clone(sets), expires: time.Now().Add(ttl)}
+}
+
+// apply updates the cached record sets of a hosted zone with changes
+// Route53 accepted.
+func (c *recordCache) apply(zoneID string, changes []types.Change) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := cacheKey(zoneID)
+	c.bump(key)
+	zone, ok := c.zones[key]
+	if !ok {
+		return
+	}
+
+	for _, change := range changes {
+		if change.ResourceRecordSet == nil {
+			continue
+		}
+		set := *change.ResourceRecordSet
+		set.Name = aws.String(route53Name(aws.ToString(set.Name)))
+
+		i := slices.IndexFunc(zone.sets, func(cached types.ResourceRecordSet) bool {
+			return sameRecordSet(cached, set)
+		})
+		switch {
+		case change.Action == types.ChangeActionDelete && i >= 0:
+			zone.sets = slices.Delete(zone.sets, i, i+1)
+		case change.Action == types.ChangeActionDelete:
+		case i >= 0:
+			zone.sets[i] = set
+		default:
+			zone.sets = append(zone.sets, set)
+		}
+	}
+}
+
+// invalidate drops the cached record sets of a hosted zone.
+func (c *recordCache) invalidate(zoneID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := cacheKey(zoneID)
+	c.bump(key)
+	delete(c.zones, key)
+}
+
+// bump records an update of a hosted zone. It must be called with mu held.
+func (c *recordCache) bump(key string) {
+	if c.versions == nil {
+		c.versions = make(map[string]uint64)
+	}
+	c.versions[key]++
+}
+
+// cacheKey returns the hosted zone ID without its "/hostedzone/" prefix.
+func cacheKey(zoneID string) string {
+	return strings.TrimPrefix(zoneID, hostedZonePrefix)
+}
+
+// sameRecordSet reports whether two record sets have the same name, type and
+// set identifier, which identify a record set in a hosted zone.
+func sameRecordSet(a, b types.ResourceRecordSet) bool {
+	return strings.EqualFold(aws.ToString(a.Name), aws.ToString(b.Name)) &&
+		a.Type == b.Type &&
+		aws.ToString(a.SetIdentifier) == aws.ToString(b.SetIdentifier)
+}
+
+// route53Name returns a record set name as Route53 lists it: lowercased,
+// with the characters other than letters, digits, hyphens, underscores and
+// dots written as \three-digit octal escapes, such as \052 for *.
+func route53Name(name string) string {
+	name = strings.ToLower(unquote(name))
+	var sb strings.Builder
+	for i := range len(name) {
+		c := name[i]
+		switch {
+		case c >= 'a' && c <= 'z', c >= '0' && c <= '9', c == '-', c == '_', c == '.':
+			sb.WriteByte(c)
+		default:
+			_, _ = fmt.Fprintf(&sb, "\\%03o", c)
+		}
+	}
+	return sb.String()
+}
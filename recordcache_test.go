@@ -0,0 +1,138 @@
+package route53 //nolint:testpackage // Testing internal functions
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/route53/types"
+	"github.com/libdns/libdns"
+)
+
+const (
+	changeResourceRecordSetsResponse = `<ChangeResourceRecordSetsResponse ` +
+		`xmlns="https://route53.amazonaws.com/doc/2013-04-01/"><ChangeInfo><Id>/change/C1</Id>` +
+		`<Status>PENDING</Status><SubmittedAt>2024-01-02T03:04:05Z</SubmittedAt></ChangeInfo>` +
+		`</ChangeResourceRecordSetsResponse>`
+	invalidChangeBatchResponse = `<ErrorResponse><Error><Type>Sender</Type><Code>InvalidChangeBatch</Code>` +
+		`<Message>rejected</Message></Error><RequestId>req-1</RequestId></ErrorResponse>`
+)
+
+func TestRecordCache(t *testing.T) {
+	provider := &Provider{HostedZoneID: "Z1", RecordCacheTTL: time.Hour}
+	canned := newCannedProvider(t, provider,
+		cannedResponse{status: http.StatusOK, body: listRecordSetsResponse(
+			recordSetXML("www.example.com.", "A", "192.0.2.1"),
+		)},
+		cannedResponse{status: http.StatusOK, body: changeResourceRecordSetsResponse},
+		cannedResponse{status: http.StatusBadRequest, body: invalidChangeBatchResponse},
+		cannedResponse{status: http.StatusOK, body: listRecordSetsResponse(
+			recordSetXML("www.example.com.", "A", "192.0.2.1"),
+		)},
+		cannedResponse{status: http.StatusOK, body: listRecordSetsResponse(
+			recordSetXML("www.example.com.", "A", "192.0.2.1"),
+			recordSetXML("mail.example.com.", "A", "192.0.2.2"),
+		)},
+	)
+	ctx := context.Background()
+	names := func() []string {
+		t.Helper()
+		records, err := provider.GetRecords(ctx, "example.com.")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		var got []string
+		for _, record := range records {
+			got = append(got, record.RR().Name)
+		}
+		return got
+	}
+
+	if got := names(); !slices.Equal(got, []string{"www"}) {
+		t.Fatalf("unexpected records %v", got)
+	}
+
+	// the existing values come from the cache, which gets the new record
+	challenge := libdns.TXT{Name: "_acme-challenge", TTL: time.Minute, Text: "token"}
+	if _, err := provider.AppendRecords(ctx, "example.com.", []libdns.Record{challenge}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := names(); !slices.Equal(got, []string{"_acme-challenge", "www"}) {
+		t.Fatalf("expected the appended record cached, got %v", got)
+	}
+	if len(canned.requests) != 2 {
+		t.Fatalf("expected the zone listed once, got %d requests", len(canned.requests))
+	}
+
+	// a failed change drops the cache
+	if _, err := provider.DeleteRecords(ctx, "example.com.", []libdns.Record{challenge}); err == nil {
+		t.Fatal("expected the change to fail")
+	}
+	if got := names(); !slices.Equal(got, []string{"www"}) {
+		t.Fatalf("expected the zone listed again, got %v", got)
+	}
+	if len(canned.requests) != 4 {
+		t.Errorf("expected 4 requests, got %d", len(canned.requests))
+	}
+
+	// snapshots always list the zone
+	snapshot, err := provider.Snapshot(ctx, "example.com.")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(snapshot.RecordSets) != 2 || len(canned.requests) != 5 {
+		t.Errorf("expected the zone listed for the snapshot, got %d record sets and %d requests",
+			len(snapshot.RecordSets), len(canned.requests))
+	}
+}
+
+func TestRecordCacheApply(t *testing.T) {
+	var cache recordCache
+	_, version, _ := cache.get("/hostedzone/Z1")
+	cache.put("/hostedzone/Z1", version, []types.ResourceRecordSet{
+		{Name: aws.String(`\052.example.com.`), Type: types.RRTypeA},
+		{Name: aws.String("www.example.com."), Type: types.RRTypeA, SetIdentifier: aws.String("blue")},
+	}, time.Hour)
+
+	cache.apply("Z1", []types.Change{
+		{Action: types.ChangeActionDelete, ResourceRecordSet: &types.ResourceRecordSet{
+			Name: aws.String("*.Example.com."), Type: types.RRTypeA,
+		}},
+		{Action: types.ChangeActionUpsert, ResourceRecordSet: &types.ResourceRecordSet{
+			Name: aws.String("www.example.com."), Type: types.RRTypeA, SetIdentifier: aws.String("green"),
+		}},
+	})
+
+	sets, _, ok := cache.get("Z1")
+	if !ok || len(sets) != 2 {
+		t.Fatalf("expected 2 cached record sets, got %d", len(sets))
+	}
+	if aws.ToString(sets[0].SetIdentifier) != "blue" || aws.ToString(sets[1].SetIdentifier) != "green" {
+		t.Errorf("expected the wildcard deleted and the green set added, got %+v", sets)
+	}
+
+	// a listing that raced with a change is not cached
+	cache.invalidate("Z1")
+	_, version, _ = cache.get("Z1")
+	cache.apply("Z1", nil)
+	cache.put("Z1", version, sets, time.Hour)
+	if _, _, ok = cache.get("Z1"); ok {
+		t.Error("expected the stale listing dropped")
+	}
+}
+
+func TestRoute53Name(t *testing.T) {
+	for name, expected := range map[string]string{
+		"WWW.example.com.":   "www.example.com.",
+		"*.example.com.":     `\052.example.com.`,
+		`\052.example.com.`:  `\052.example.com.`,
+		"_acme-challenge.a.": "_acme-challenge.a.",
+	} {
+		if got := route53Name(name); got != expected {
+			t.Errorf("expected %q for %q, got %q", expected, name, got)
+		}
+	}
+}
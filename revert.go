@@ -0,0 +1,198 @@
+package route53
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/service/route53/types"
+	"github.com/libdns/libdns"
+)
+
+// ErrRevertConflict is returned by Revert when a record set no longer holds
+// the values a change left it with, because it was modified since.
+var ErrRevertConflict = errors.New("record set modified since the change")
+
+// Revert undoes changes obtained from a ChangeRecorder: every record set they
+// modified is restored to its values and TTL from before the first of them,
+// or deleted if it did not exist. Passing all the changes recorded during a
+// call, such as a SetRecords deployment, reverts the whole call.
+//
+// Before touching a hosted zone, Revert checks that each of its record sets
+// still holds the values and TTL the last change left it with, and returns
+// ErrRevertConflict otherwise. The inverse changes are batched like those of
+// Restore, and returned so a revert can itself be reverted.
+func (p *Provider) Revert(ctx context.Context, changes ...Change) (_ []Change, err error) {
+	ctx, end := p.startSpan(ctx, "Revert", changeCountAttributes(changes))
+	defer end(&err)
+
+	ctx = withOperation(ctx, OperationRevert)
+
+	plans, err := planReverts(changes)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, waitDeferred := p.deferSync(ctx)
+
+	var submitted []Change
+	for _, plan := range plans {
+		reverted, revertErr := p.revertZone(ctx, plan)
+		submitted = append(submitted, reverted...)
+		if revertErr != nil {
+			return submitted, revertErr
+		}
+	}
+
+	if err = waitDeferred(); err != nil {
+		return submitted, err
+	}
+	return submitted, nil
+}
+
+// revertPlan holds what reverting changes to a single hosted zone takes.
+type revertPlan struct {
+	zone, zoneID string
+
+	// keys are the record sets to revert, in order.
+	keys []recordSetKey
+
+	// restore are the values to restore; expected are the values the
+	// record sets must currently hold.
+	restore, expected map[recordSetKey][]libdns.Record
+}
+
+// planReverts groups the record sets modified by changes per hosted zone. For
+// a record set modified several times, the values before the earliest change
+// are restored and the values after the latest are expected.
+func planReverts(changes []Change) ([]*revertPlan, error) {
+	var plans []*revertPlan
+	byZone := make(map[string]*revertPlan)
+
+	for i := len(changes) - 1; i >= 0; i-- {
+		change := changes[i]
+		if change.HostedZoneID == "" || len(change.RecordSets) == 0 {
+			return nil, fmt.Errorf(
+				"change %s has no record sets to revert; only changes from a ChangeRecorder can be reverted",
+				change.ID,
+			)
+		}
+
+		plan, ok := byZone[change.HostedZoneID]
+		if !ok {
+			plan = &revertPlan{
+				zone:     change.Zone,
+				zoneID:   change.HostedZoneID,
+				restore:  make(map[recordSetKey][]libdns.Record),
+				expected: make(map[recordSetKey][]libdns.Record),
+			}
+			byZone[change.HostedZoneID] = plan
+			plans = append(plans, plan)
+		}
+
+		for j := len(change.RecordSets) - 1; j >= 0; j-- {
+			set := change.RecordSets[j]
+			key := recordSetKey{name: set.Name, recordType: set.Type}
+			if _, seen := plan.expected[key]; !seen {
+				plan.keys = append(plan.keys, key)
+				plan.expected[key] = set.After
+			}
+			plan.restore[key] = set.Before
+		}
+	}
+	return plans, nil
+}
+
+// revertZone restores the record sets of a plan under their per-tuple locks.
+func (p *Provider) revertZone(ctx context.Context, plan *revertPlan) ([]Change, error) {
+	unlock, err := p.lockSets(ctx, plan.zoneID, plan.keys)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	existing, err := p.getRecords(ctx, plan.zoneID, plan.zone)
+	if err != nil {
+		return nil, err
+	}
+	current := p.groupRecordsByKey(existing)
+
+	var changes []types.Change
+	var sets []RecordSetChange
+	for _, key := range plan.keys {
+		expected, canonicalErr := canonicalRecords(plan.zone, key, plan.expected[key])
+		if canonicalErr != nil {
+			return nil, canonicalErr
+		}
+		if !sameValues(current[key], expected) {
+			return nil, fmt.Errorf("%w: %s %s", ErrRevertConflict, key.name, key.recordType)
+		}
+
+		action, values := types.ChangeActionUpsert, plan.restore[key]
+		if len(values) == 0 {
+			action, values = types.ChangeActionDelete, current[key]
+		}
+		if len(values) == 0 {
+			continue // created and deleted again
+		}
+
+		set, buildErr := buildRecordSet(plan.zone, key.name, key.recordType, values)
+		if buildErr != nil {
+			return nil, buildErr
+		}
+		changes = append(changes, types.Change{Action: action, ResourceRecordSet: set})
+		sets = append(sets, RecordSetChange{
+			Name:   key.name,
+			Type:   key.recordType,
+			Before: current[key],
+			After:  plan.restore[key],
+		})
+	}
+	if len(changes) == 0 {
+		return nil, nil
+	}
+
+	p.Logger.DebugContext(ctx, "reverting changes",
+		"zone", plan.zone, "record_sets", len(changes))
+
+	return p.submitChanges(ctx, plan.zoneID, plan.zone, changes, sets)
+}
+
+// canonicalRecords returns records the way Route53 lists them back, such as
+// with its spacing and address formatting, by converting them to a record set
+// and back.
+func canonicalRecords(zone string, key recordSetKey, records []libdns.Record) ([]libdns.Record, error) {
+	if len(records) == 0 {
+		return nil, nil
+	}
+	set, err := buildRecordSet(zone, key.name, key.recordType, records)
+	if err != nil {
+		return nil, err
+	}
+	return parseRecordSet(*set, zone)
+}
+
+// sameValues reports whether two lists of records of the same record set hold
+// the same values, in any order, and the same TTL. Route53 keeps a single TTL
+// per record set, the one of the first record written, so only the TTLs of
+// the first records are compared.
+func sameValues(a, b []libdns.Record) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	if len(a) > 0 && a[0].RR().TTL != b[0].RR().TTL {
+		return false
+	}
+	values := make(map[string]int)
+	for _, record := range a {
+		values[record.RR().Data]++
+	}
+	for _, record := range b {
+		data := record.RR().Data
+		if values[data] == 0 {
+			return false
+		}
+		values[data]--
+	}
+	return true
+}
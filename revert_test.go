@@ -0,0 +1,109 @@
+package route53 //nolint:testpackage // Testing internal functions
+
+import (
+	"context"
+	"net/http"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+func TestPlanReverts(t *testing.T) {
+	v1 := []libdns.Record{libdns.Address{Name: "www", TTL: time.Hour, IP: netip.MustParseAddr("192.0.2.1")}}
+	v2 := []libdns.Record{libdns.Address{Name: "www", TTL: time.Minute, IP: netip.MustParseAddr("192.0.2.2")}}
+	v3 := []libdns.Record{libdns.Address{Name: "www", TTL: time.Minute, IP: netip.MustParseAddr("192.0.2.3")}}
+	txt := []libdns.Record{libdns.TXT{Name: "_acme-challenge", TTL: time.Minute, Text: "token"}}
+
+	www := recordSetKey{name: "www", recordType: "A"}
+	challenge := recordSetKey{name: "_acme-challenge", recordType: "TXT"}
+
+	plans, err := planReverts([]Change{
+		{ID: "C1", Zone: "example.com.", HostedZoneID: "Z1", RecordSets: []RecordSetChange{
+			{Name: "www", Type: "A", Before: v1, After: v2},
+		}},
+		{ID: "C2", Zone: "example.com.", HostedZoneID: "Z1", RecordSets: []RecordSetChange{
+			{Name: "_acme-challenge", Type: "TXT", After: txt},
+			{Name: "www", Type: "A", Before: v2, After: v3},
+		}},
+		{ID: "C3", Zone: "example.org.", HostedZoneID: "Z2", RecordSets: []RecordSetChange{
+			{Name: "www", Type: "A", Before: v1},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(plans) != 2 || plans[0].zoneID != "Z2" || plans[1].zoneID != "Z1" {
+		t.Fatalf("expected plans for Z2 then Z1, got %+v", plans)
+	}
+
+	plan := plans[1]
+	if len(plan.keys) != 2 || plan.keys[0] != www || plan.keys[1] != challenge {
+		t.Errorf("unexpected keys: %v", plan.keys)
+	}
+	// the values before the earliest change are restored, with their TTL
+	if !sameValues(plan.restore[www], v1) || plan.restore[www][0].RR().TTL != time.Hour {
+		t.Errorf("expected %v restored, got %v", v1, plan.restore[www])
+	}
+	// the values after the latest change are expected
+	if !sameValues(plan.expected[www], v3) {
+		t.Errorf("expected %v expected, got %v", v3, plan.expected[www])
+	}
+	if len(plan.restore[challenge]) != 0 || !sameValues(plan.expected[challenge], txt) {
+		t.Errorf("expected the challenge to be deleted, got %v", plan.restore[challenge])
+	}
+
+	if _, err = planReverts([]Change{{ID: "/change/C4"}}); err == nil {
+		t.Error("expected an error for a change without record sets")
+	}
+}
+
+func TestSameValues(t *testing.T) {
+	a := libdns.TXT{Name: "x", TTL: time.Minute, Text: "a"}
+	b := libdns.TXT{Name: "x", TTL: time.Minute, Text: "b"}
+	c := libdns.TXT{Name: "x", TTL: time.Minute, Text: "c"}
+	longer := libdns.TXT{Name: "x", TTL: time.Hour, Text: "a"}
+
+	if !sameValues([]libdns.Record{a, b}, []libdns.Record{b, a}) {
+		t.Error("expected order to be ignored")
+	}
+	if sameValues([]libdns.Record{a, b}, []libdns.Record{a, c}) || sameValues([]libdns.Record{a}, []libdns.Record{a, a}) {
+		t.Error("expected different values to differ")
+	}
+	if sameValues([]libdns.Record{a}, []libdns.Record{longer}) {
+		t.Error("expected different TTLs to differ")
+	}
+	if !sameValues(nil, nil) {
+		t.Error("expected empty record sets to be the same")
+	}
+}
+
+func TestRevertCanonicalValues(t *testing.T) {
+	provider := &Provider{HostedZoneID: "Z1"}
+	canned := newCannedProvider(t, provider,
+		cannedResponse{status: http.StatusOK, body: listRecordSetsResponse(
+			recordSetXML("www.example.com.", "AAAA", "2001:db8::1"),
+			recordSetXML("example.com.", "MX", "10 mail.example.com."),
+		)},
+		cannedResponse{status: http.StatusOK, body: changeResourceRecordSetsResponse},
+	)
+
+	// the values the caller wrote, which Route53 lists back in its own form
+	aaaa := []libdns.Record{libdns.RR{Name: "www", Type: "AAAA", TTL: 300 * time.Second, Data: "2001:DB8:0::1"}}
+	mx := []libdns.Record{libdns.RR{Name: "@", Type: "MX", TTL: 300 * time.Second, Data: "10  mail.example.com."}}
+	changes, err := provider.Revert(context.Background(), Change{
+		ID: "/change/C1", Zone: "example.com.", HostedZoneID: hostedZonePrefix + "Z1",
+		RecordSets: []RecordSetChange{
+			{Name: "www", Type: "AAAA", After: aaaa},
+			{Name: "@", Type: "MX", After: mx},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(changes) != 1 || strings.Count(canned.bodies[1], "<Action>DELETE</Action>") != 2 {
+		t.Errorf("expected both record sets deleted, got %d changes:\n%s", len(changes), canned.bodies[1])
+	}
+}
@@ -0,0 +1,185 @@
+package route53
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+// SerializedRecord is the stable, format-neutral form of a record, for
+// describing records as data in JSON or YAML files, APIs and the
+// route53dns command. In JSON, an A record and an alias look like this:
+//
+//	{"name": "www", "type": "A", "ttl": 300, "data": "192.0.2.1"}
+//	{"name": "cdn", "type": "A", "alias": {"target": "d111111abcdef8.cloudfront.net.", "hosted_zone_id": "Z2FDTNDATAQYW2"}}
+//
+// Data holds the unescaped zone file data of the record, as in libdns.RR, so
+// any record type can be described. Decoding parses it into the typed
+// libdns records, such as libdns.Address or libdns.MX, like GetRecords does.
+type SerializedRecord struct {
+	// Name is the record name, relative to the zone; "@" for the apex.
+	Name string `json:"name" yaml:"name"`
+
+	// Type is the record type, such as "A" or "TXT".
+	Type string `json:"type" yaml:"type"`
+
+	// TTL is the time to live, in seconds. Aliases have none.
+	TTL int64 `json:"ttl,omitempty" yaml:"ttl,omitempty"`
+
+	// Data is the record data, empty for aliases.
+	Data string `json:"data,omitempty" yaml:"data,omitempty"`
+
+	// Alias is set for Route53 alias records.
+	Alias *SerializedAlias `json:"alias,omitempty" yaml:"alias,omitempty"`
+}
+
+// SerializedAlias holds the target of a serialized Alias record.
+type SerializedAlias struct {
+	Target               string `json:"target" yaml:"target"`
+	HostedZoneID         string `json:"hosted_zone_id" yaml:"hosted_zone_id"`
+	EvaluateTargetHealth bool   `json:"evaluate_target_health,omitempty" yaml:"evaluate_target_health,omitempty"`
+}
+
+// SerializeRecord converts a record into its serialized form.
+func SerializeRecord(record libdns.Record) SerializedRecord {
+	if alias, ok := asAlias(record); ok {
+		return SerializedRecord{
+			Name: alias.Name,
+			Type: alias.Type,
+			Alias: &SerializedAlias{
+				Target:               alias.Target,
+				HostedZoneID:         alias.HostedZoneID,
+				EvaluateTargetHealth: alias.EvaluateTargetHealth,
+			},
+		}
+	}
+
+	rr := record.RR()
+	return SerializedRecord{
+		Name: rr.Name,
+		Type: rr.Type,
+		TTL:  int64(rr.TTL / time.Second),
+		Data: rr.Data,
+	}
+}
+
+// Record converts the serialized form back into a record: an Alias, a typed
+// libdns record for the types libdns knows, or a libdns.RR otherwise.
+func (s SerializedRecord) Record() (libdns.Record, error) {
+	switch {
+	case s.Name == "":
+		return nil, errors.New("record without a name")
+	case s.Type == "":
+		return nil, fmt.Errorf("record %s without a type", s.Name)
+	case s.TTL < 0:
+		return nil, fmt.Errorf("record %s %s has a negative TTL", s.Name, s.Type)
+	}
+
+	if s.Alias != nil {
+		if s.Data != "" || s.TTL != 0 {
+			return nil, fmt.Errorf("alias record %s %s cannot have data or a TTL", s.Name, s.Type)
+		}
+		return Alias{
+			Name:                 s.Name,
+			Type:                 s.Type,
+			Target:               s.Alias.Target,
+			HostedZoneID:         s.Alias.HostedZoneID,
+			EvaluateTargetHealth: s.Alias.EvaluateTargetHealth,
+		}, nil
+	}
+
+	rr := libdns.RR{
+		Name: s.Name,
+		TTL:  time.Duration(s.TTL) * time.Second,
+		Type: s.Type,
+		Data: s.Data,
+	}
+	record, err := rr.Parse()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s record %s: %w", s.Type, s.Name, err)
+	}
+	return record, nil
+}
+
+// Records is a list of records that encodes to and decodes from JSON, and
+// from YAML with libraries supporting the MarshalYAML and UnmarshalYAML
+// methods of gopkg.in/yaml.v2 (which gopkg.in/yaml.v3 also honors), as a
+// list of SerializedRecord. Use it as a field type in configuration structs.
+type Records []libdns.Record
+
+// Serialize converts the records into their serialized form.
+func (r Records) Serialize() []SerializedRecord {
+	serialized := make([]SerializedRecord, 0, len(r))
+	for _, record := range r {
+		serialized = append(serialized, SerializeRecord(record))
+	}
+	return serialized
+}
+
+// MarshalJSON implements json.Marshaler.
+func (r Records) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.Serialize())
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (r *Records) UnmarshalJSON(data []byte) error {
+	var serialized []SerializedRecord
+	if err := json.Unmarshal(data, &serialized); err != nil {
+		return err
+	}
+	records, err := deserializeRecords(serialized)
+	if err != nil {
+		return err
+	}
+	*r = records
+	return nil
+}
+
+// MarshalYAML implements the yaml.Marshaler interface of YAML libraries.
+func (r Records) MarshalYAML() (any, error) {
+	return r.Serialize(), nil
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface of YAML libraries.
+func (r *Records) UnmarshalYAML(unmarshal func(any) error) error {
+	var serialized []SerializedRecord
+	if err := unmarshal(&serialized); err != nil {
+		return err
+	}
+	records, err := deserializeRecords(serialized)
+	if err != nil {
+		return err
+	}
+	*r = records
+	return nil
+}
+
+// deserializeRecords decodes serialized records.
+func deserializeRecords(serialized []SerializedRecord) (Records, error) {
+	records := make(Records, 0, len(serialized))
+	for i, s := range serialized {
+		record, err := s.Record()
+		if err != nil {
+			return nil, fmt.Errorf("record %d: %w", i, err)
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// MarshalRecords encodes records as a JSON array of SerializedRecord.
+func MarshalRecords(records []libdns.Record) ([]byte, error) {
+	return json.Marshal(Records(records))
+}
+
+// UnmarshalRecords decodes a JSON array of SerializedRecord into records.
+func UnmarshalRecords(data []byte) ([]libdns.Record, error) {
+	var records Records
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
@@ -0,0 +1,116 @@
+package route53 //nolint:testpackage // Testing internal functions
+
+import (
+	"encoding/json"
+	"net/netip"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+func TestRecordsRoundTrip(t *testing.T) {
+	records := []libdns.Record{
+		libdns.Address{Name: "www", TTL: 300 * time.Second, IP: netip.MustParseAddr("192.0.2.1")},
+		libdns.Address{Name: "www", TTL: 300 * time.Second, IP: netip.MustParseAddr("2001:db8::1")},
+		libdns.TXT{Name: "_acme-challenge", TTL: 60 * time.Second, Text: `quotes " and \ backslashes`},
+		libdns.MX{Name: "@", TTL: time.Hour, Preference: 10, Target: "mx.example.com."},
+		libdns.SRV{
+			Service: "sip", Transport: "tcp", Name: "@", TTL: time.Hour,
+			Priority: 0, Weight: 5, Port: 5060, Target: "sip.example.com.",
+		},
+		libdns.CAA{Name: "@", TTL: time.Hour, Flags: 0, Tag: "issue", Value: "letsencrypt.org"},
+		libdns.NS{Name: "sub", TTL: 172800 * time.Second, Target: "ns-1.awsdns-01.org."},
+		libdns.CNAME{Name: "blog", TTL: 300 * time.Second, Target: "example.com."},
+		libdns.ServiceBinding{
+			Scheme: "https", Name: "@", TTL: 300 * time.Second, Priority: 1, Target: ".",
+			Params: libdns.SvcParams{"alpn": {"h2", "h3"}},
+		},
+		libdns.RR{Name: "@", TTL: 900 * time.Second, Type: "SOA", Data: "ns-1.awsdns-01.org. hostmaster. 1 7200 900 1209600 86400"},
+		Alias{Name: "cdn", Type: "A", Target: "d111111abcdef8.cloudfront.net.", HostedZoneID: "Z2FDTNDATAQYW2", EvaluateTargetHealth: true},
+	}
+
+	data, err := MarshalRecords(records)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	decoded, err := UnmarshalRecords(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v\n%s", err, data)
+	}
+	if !reflect.DeepEqual(decoded, records) {
+		t.Errorf("records changed in round trip:\nexpected %#v\ngot      %#v", records, decoded)
+	}
+}
+
+func TestSerializedRecordFormat(t *testing.T) {
+	data, err := json.Marshal(Records{
+		libdns.Address{Name: "www", TTL: 300 * time.Second, IP: netip.MustParseAddr("192.0.2.1")},
+		Alias{Name: "cdn", Type: "A", Target: "d111111abcdef8.cloudfront.net.", HostedZoneID: "Z2FDTNDATAQYW2"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := `[{"name":"www","type":"A","ttl":300,"data":"192.0.2.1"},` +
+		`{"name":"cdn","type":"A","alias":{"target":"d111111abcdef8.cloudfront.net.","hosted_zone_id":"Z2FDTNDATAQYW2"}}]`
+	if string(data) != expected {
+		t.Errorf("expected %s, got %s", expected, data)
+	}
+}
+
+func TestRecordsYAML(t *testing.T) {
+	records := Records{libdns.TXT{Name: "@", TTL: time.Minute, Text: "hello"}}
+
+	marshaled, err := records.MarshalYAML()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	serialized, ok := marshaled.([]SerializedRecord)
+	if !ok || len(serialized) != 1 || serialized[0].Data != "hello" {
+		t.Fatalf("unexpected YAML value: %#v", marshaled)
+	}
+
+	// stand-in for a YAML library decoding a document into the value
+	var decoded Records
+	err = decoded.UnmarshalYAML(func(v any) error {
+		*v.(*[]SerializedRecord) = serialized
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(decoded, records) {
+		t.Errorf("expected %#v, got %#v", records, decoded)
+	}
+}
+
+func TestUnmarshalRecordsErrors(t *testing.T) {
+	cases := []struct {
+		name     string
+		input    string
+		contains string
+	}{
+		{name: "missing name", input: `[{"type":"A","data":"192.0.2.1"}]`, contains: "record 0: record without a name"},
+		{name: "missing type", input: `[{"name":"www","data":"192.0.2.1"}]`, contains: "without a type"},
+		{name: "negative TTL", input: `[{"name":"www","type":"A","ttl":-1,"data":"192.0.2.1"}]`, contains: "negative TTL"},
+		{name: "invalid data", input: `[{"name":"www","type":"A","data":"nope"}]`, contains: "failed to parse A record www"},
+		{
+			name:     "alias with TTL",
+			input:    `[{"name":"cdn","type":"A","ttl":60,"alias":{"target":"x.","hosted_zone_id":"Z"}}]`,
+			contains: "cannot have data or a TTL",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := UnmarshalRecords([]byte(c.input))
+			if err == nil || !strings.Contains(err.Error(), c.contains) {
+				t.Errorf("expected error containing %q, got %v", c.contains, err)
+			}
+		})
+	}
+}
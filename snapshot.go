@@ -0,0 +1,465 @@
+package route53
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/route53/types"
+	"github.com/libdns/libdns"
+)
+
+// Snapshot is the complete content of a hosted zone at a point in time. Unlike
+// GetRecords, it keeps everything Route53 stores about each record set,
+// including alias targets and routing policies, so Restore can bring the zone
+// back exactly. It encodes to JSON.
+type Snapshot struct {
+	// Zone is the name of the zone the snapshot was taken of.
+	Zone string `json:"zone"`
+
+	// HostedZoneID is the ID of the hosted zone the snapshot was taken of.
+	HostedZoneID string `json:"hosted_zone_id"`
+
+	// Time is when the snapshot was taken.
+	Time time.Time `json:"time"`
+
+	// RecordSets are the record sets of the zone, in the order Route53
+	// lists them.
+	RecordSets []SnapshotRecordSet `json:"record_sets"`
+}
+
+// SnapshotRecordSet is a record set as Route53 stores it.
+type SnapshotRecordSet struct {
+	// Name is the record set name relative to the zone, with special
+	// characters escaped the way Route53 returns them.
+	Name string `json:"name"`
+
+	// Type is the record set type.
+	Type string `json:"type"`
+
+	// TTL is the time to live in seconds. Aliases have none.
+	TTL *int64 `json:"ttl,omitempty"`
+
+	// Values are the values of the record set, in Route53 syntax; for
+	// example TXT values are quoted.
+	Values []string `json:"values,omitempty"`
+
+	// Alias is set for alias record sets.
+	Alias *SerializedAlias `json:"alias,omitempty"`
+
+	// SetIdentifier tells apart the record sets of a routing policy that
+	// share a name and type.
+	SetIdentifier string `json:"set_identifier,omitempty"`
+
+	// Weight is set for weighted routing.
+	Weight *int64 `json:"weight,omitempty"`
+
+	// Region is set for latency-based routing.
+	Region string `json:"region,omitempty"`
+
+	// Failover is PRIMARY or SECONDARY for failover routing.
+	Failover string `json:"failover,omitempty"`
+
+	// GeoLocation is set for geolocation routing.
+	GeoLocation *SnapshotGeoLocation `json:"geo_location,omitempty"`
+
+	// GeoProximity is set for geoproximity routing.
+	GeoProximity *SnapshotGeoProximity `json:"geo_proximity,omitempty"`
+
+	// CIDRRouting is set for IP-based routing.
+	CIDRRouting *SnapshotCIDRRouting `json:"cidr_routing,omitempty"`
+
+	// MultiValueAnswer is set for multivalue answer routing.
+	MultiValueAnswer *bool `json:"multi_value_answer,omitempty"`
+
+	// HealthCheckID is the ID of the health check associated with the
+	// record set.
+	HealthCheckID string `json:"health_check_id,omitempty"`
+
+	// TrafficPolicyInstanceID is set for record sets created by a traffic
+	// policy instance. Restore leaves them alone.
+	TrafficPolicyInstanceID string `json:"traffic_policy_instance_id,omitempty"`
+}
+
+// SnapshotGeoLocation is the location of a geolocation routing record set.
+type SnapshotGeoLocation struct {
+	ContinentCode   string `json:"continent_code,omitempty"`
+	CountryCode     string `json:"country_code,omitempty"`
+	SubdivisionCode string `json:"subdivision_code,omitempty"`
+}
+
+// SnapshotGeoProximity is the location of a geoproximity routing record set.
+type SnapshotGeoProximity struct {
+	AWSRegion      string `json:"aws_region,omitempty"`
+	LocalZoneGroup string `json:"local_zone_group,omitempty"`
+	Latitude       string `json:"latitude,omitempty"`
+	Longitude      string `json:"longitude,omitempty"`
+	Bias           *int32 `json:"bias,omitempty"`
+}
+
+// SnapshotCIDRRouting is the CIDR location of an IP-based routing record set.
+type SnapshotCIDRRouting struct {
+	CollectionID string `json:"collection_id"`
+	LocationName string `json:"location_name"`
+}
+
+// Snapshot captures every record set of the zone.
+func (p *Provider) Snapshot(ctx context.Context, zone string) (_ Snapshot, err error) {
+	ctx, end := p.startSpan(ctx, "Snapshot", zoneAttributes(zone, nil))
+	defer end(&err)
+
+	zone = absoluteZoneName(zone)
+	zoneID, err := p.getZoneID(ctx, zone)
+	if err != nil {
+		return Snapshot{}, err
+	}
+
+	sets, err := p.listRecordSets(ctx, zoneID)
+	if err != nil {
+		return Snapshot{}, err
+	}
+
+	snapshot := Snapshot{
+		Zone:         zone,
+		HostedZoneID: strings.TrimPrefix(zoneID, hostedZonePrefix),
+		Time:         time.Now().UTC(),
+		RecordSets:   make([]SnapshotRecordSet, 0, len(sets)),
+	}
+	for _, set := range sets {
+		snapshot.RecordSets = append(snapshot.RecordSets, snapshotRecordSet(set, zone))
+	}
+
+	p.Logger.DebugContext(ctx, "took zone snapshot", "zone", zone, "record_sets", len(sets))
+
+	return snapshot, nil
+}
+
+// Restore reconciles the zone with the snapshot: record sets that differ from
+// the snapshot are UPSERTed, those missing from the zone are created and those
+// absent from the snapshot are deleted, batching the changes within the
+// ChangeResourceRecordSets limits. Record sets created by traffic policy
+// instances are left alone. The snapshot may be restored into another hosted
+// zone, in which case its apex SOA and NS record sets, which belong to the
+// original hosted zone, are skipped, and its aliases to record sets of the
+// original hosted zone point at the other one. The record sets are changed
+// under their per-tuple locks, taken in a fixed order.
+//
+// Restore returns the submitted changes. They are reported to the Journal
+// and to the context's ChangeRecorder with their values only, so Revert
+// cannot bring back the routing policy of the record sets they touched;
+// take another snapshot before restoring instead.
+func (p *Provider) Restore(ctx context.Context, zone string, snapshot Snapshot) (_ []Change, err error) {
+	ctx, end := p.startSpan(ctx, "Restore", zoneAttributes(zone, nil))
+	defer end(&err)
+
+	ctx = withOperation(ctx, OperationRestore)
+
+	zone = absoluteZoneName(zone)
+	zoneID, err := p.getZoneID(ctx, zone)
+	if err != nil {
+		return nil, err
+	}
+
+	sameZone := strings.TrimPrefix(zoneID, hostedZonePrefix) == strings.TrimPrefix(snapshot.HostedZoneID, hostedZonePrefix)
+	if !sameZone {
+		snapshot = retargetAliases(snapshot, strings.TrimPrefix(zoneID, hostedZonePrefix))
+	}
+
+	changes, sets, unlock, err := p.planLocked(ctx, zoneID,
+		func(current []types.ResourceRecordSet) ([]types.Change, []RecordSetChange, error) {
+			return planRestore(zone, current, snapshot, sameZone)
+		})
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	if len(changes) == 0 {
+		return nil, nil
+	}
+
+	p.Logger.DebugContext(ctx, "restoring zone snapshot",
+		"zone", zone, "snapshot_time", snapshot.Time, "changes", len(changes))
+
+	ctx, waitDeferred := p.deferSync(ctx)
+
+	submitted, err := p.submitChanges(ctx, zoneID, zone, changes, sets)
+	unlock()
+	if err != nil {
+		return submitted, err
+	}
+
+	if err = waitDeferred(); err != nil {
+		return submitted, err
+	}
+	return submitted, nil
+}
+
+// planLocked plans changes to the record sets of a hosted zone with plan,
+// then locks the record sets the changes touch and plans again under the
+// locks, until the locks cover the plan. The locks are taken in a fixed order
+// and held until unlock is called.
+func (p *Provider) planLocked(
+	ctx context.Context,
+	zoneID string,
+	plan func(current []types.ResourceRecordSet) ([]types.Change, []RecordSetChange, error),
+) ([]types.Change, []RecordSetChange, func(), error) {
+	unlock := func() {}
+	locked := make(map[recordSetKey]bool)
+	for {
+		current, err := p.listRecordSets(ctx, zoneID)
+		if err != nil {
+			unlock()
+			return nil, nil, nil, err
+		}
+		changes, sets, err := plan(current)
+		if err != nil {
+			unlock()
+			return nil, nil, nil, err
+		}
+
+		covered := true
+		for _, set := range sets {
+			// lock the name as the record methods do, unescaped, so
+			// wildcards (\052) contend with them
+			key := recordSetKey{name: unquote(set.Name), recordType: set.Type}
+			if !locked[key] {
+				locked[key] = true
+				covered = false
+			}
+		}
+		if covered {
+			return changes, sets, unlock, nil
+		}
+
+		unlock()
+		if unlock, err = p.lockSets(ctx, zoneID, slices.Collect(maps.Keys(locked))); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+}
+
+// retargetAliases returns snapshot with its aliases to record sets of its own
+// hosted zone pointed at the hosted zone zoneID instead.
+func retargetAliases(snapshot Snapshot, zoneID string) Snapshot {
+	sourceID := strings.TrimPrefix(snapshot.HostedZoneID, hostedZonePrefix)
+	retargeted := snapshot
+	retargeted.RecordSets = make([]SnapshotRecordSet, 0, len(snapshot.RecordSets))
+	for _, set := range snapshot.RecordSets {
+		if set.Alias != nil && strings.EqualFold(set.Alias.HostedZoneID, sourceID) {
+			alias := *set.Alias
+			alias.HostedZoneID = zoneID
+			set.Alias = &alias
+		}
+		retargeted.RecordSets = append(retargeted.RecordSets, set)
+	}
+	return retargeted
+}
+
+// snapshotKey identifies a record set, including the set identifier of
+// routing policies.
+type snapshotKey struct {
+	name, recordType, setIdentifier string
+}
+
+// key returns the key identifying s.
+func (s SnapshotRecordSet) key() snapshotKey {
+	return snapshotKey{name: strings.ToLower(s.Name), recordType: s.Type, setIdentifier: s.SetIdentifier}
+}
+
+// planRestore computes the changes turning the current record sets of the
+// zone into those of the snapshot: deletions first, then UPSERTs. Unless
+// sameZone is set, the apex SOA and NS record sets are left as they are.
+func planRestore(
+	zone string,
+	current []types.ResourceRecordSet,
+	snapshot Snapshot,
+	sameZone bool,
+) ([]types.Change, []RecordSetChange, error) {
+	wanted := make(map[snapshotKey]SnapshotRecordSet, len(snapshot.RecordSets))
+	for _, set := range snapshot.RecordSets {
+		wanted[set.key()] = set
+	}
+
+	existing := make(map[snapshotKey]types.ResourceRecordSet, len(current))
+	var deletes, upserts []types.Change
+	var deleteSets, upsertSets []RecordSetChange
+
+	for _, set := range current {
+		snap := snapshotRecordSet(set, zone)
+		existing[snap.key()] = set
+		if snap.TrafficPolicyInstanceID != "" {
+			continue
+		}
+		if _, ok := wanted[snap.key()]; ok || isApexDefault(set, zone) {
+			continue
+		}
+
+		before, err := parseRecordSet(set, zone)
+		if err != nil {
+			return nil, nil, err
+		}
+		deletes = append(deletes, types.Change{Action: types.ChangeActionDelete, ResourceRecordSet: &set})
+		deleteSets = append(deleteSets, RecordSetChange{Name: snap.Name, Type: snap.Type, Before: before})
+	}
+
+	for _, snap := range snapshot.RecordSets {
+		set := snap.resourceRecordSet(zone)
+		if snap.TrafficPolicyInstanceID != "" || (!sameZone && isApexDefault(*set, zone)) {
+			continue
+		}
+
+		var before []libdns.Record
+		if set, ok := existing[snap.key()]; ok {
+			if snapshotRecordSet(set, zone).equal(snap) {
+				continue
+			}
+			var err error
+			if before, err = parseRecordSet(set, zone); err != nil {
+				return nil, nil, err
+			}
+		}
+
+		after, err := parseRecordSet(*set, zone)
+		if err != nil {
+			return nil, nil, err
+		}
+		upserts = append(upserts, types.Change{Action: types.ChangeActionUpsert, ResourceRecordSet: set})
+		upsertSets = append(upsertSets, RecordSetChange{Name: snap.Name, Type: snap.Type, Before: before, After: after})
+	}
+
+	return append(deletes, upserts...), append(deleteSets, upsertSets...), nil
+}
+
+// equal reports whether two snapshot record sets are the same, regardless of
+// the order of their values.
+func (s SnapshotRecordSet) equal(other SnapshotRecordSet) bool {
+	a, b := s, other
+	a.Values, b.Values = slices.Sorted(slices.Values(a.Values)), slices.Sorted(slices.Values(b.Values))
+	a.Name, b.Name = strings.ToLower(a.Name), strings.ToLower(b.Name)
+	return reflect.DeepEqual(a, b)
+}
+
+// snapshotRecordSet converts a Route53 record set into its snapshot form.
+func snapshotRecordSet(set types.ResourceRecordSet, zone string) SnapshotRecordSet {
+	snap := SnapshotRecordSet{
+		Name:                    libdns.RelativeName(aws.ToString(set.Name), zone),
+		Type:                    string(set.Type),
+		TTL:                     set.TTL,
+		SetIdentifier:           aws.ToString(set.SetIdentifier),
+		Weight:                  set.Weight,
+		Region:                  string(set.Region),
+		Failover:                string(set.Failover),
+		MultiValueAnswer:        set.MultiValueAnswer,
+		HealthCheckID:           aws.ToString(set.HealthCheckId),
+		TrafficPolicyInstanceID: aws.ToString(set.TrafficPolicyInstanceId),
+	}
+	for _, rr := range set.ResourceRecords {
+		snap.Values = append(snap.Values, aws.ToString(rr.Value))
+	}
+	if set.AliasTarget != nil {
+		alias := aliasFromTarget(snap.Name, snap.Type, set.AliasTarget)
+		snap.Alias = &SerializedAlias{
+			Target:               alias.Target,
+			HostedZoneID:         alias.HostedZoneID,
+			EvaluateTargetHealth: alias.EvaluateTargetHealth,
+		}
+	}
+	if g := set.GeoLocation; g != nil {
+		snap.GeoLocation = &SnapshotGeoLocation{
+			ContinentCode:   aws.ToString(g.ContinentCode),
+			CountryCode:     aws.ToString(g.CountryCode),
+			SubdivisionCode: aws.ToString(g.SubdivisionCode),
+		}
+	}
+	if g := set.GeoProximityLocation; g != nil {
+		snap.GeoProximity = &SnapshotGeoProximity{
+			AWSRegion:      aws.ToString(g.AWSRegion),
+			LocalZoneGroup: aws.ToString(g.LocalZoneGroup),
+			Bias:           g.Bias,
+		}
+		if g.Coordinates != nil {
+			snap.GeoProximity.Latitude = aws.ToString(g.Coordinates.Latitude)
+			snap.GeoProximity.Longitude = aws.ToString(g.Coordinates.Longitude)
+		}
+	}
+	if c := set.CidrRoutingConfig; c != nil {
+		snap.CIDRRouting = &SnapshotCIDRRouting{
+			CollectionID: aws.ToString(c.CollectionId),
+			LocationName: aws.ToString(c.LocationName),
+		}
+	}
+	return snap
+}
+
+// resourceRecordSet converts the snapshot form back into a Route53 record
+// set of the given zone.
+func (s SnapshotRecordSet) resourceRecordSet(zone string) *types.ResourceRecordSet {
+	set := &types.ResourceRecordSet{
+		Name:             aws.String(libdns.AbsoluteName(s.Name, zone)),
+		Type:             types.RRType(s.Type),
+		TTL:              s.TTL,
+		SetIdentifier:    optionalString(s.SetIdentifier),
+		Weight:           s.Weight,
+		Region:           types.ResourceRecordSetRegion(s.Region),
+		Failover:         types.ResourceRecordSetFailover(s.Failover),
+		MultiValueAnswer: s.MultiValueAnswer,
+		HealthCheckId:    optionalString(s.HealthCheckID),
+	}
+	for _, value := range s.Values {
+		set.ResourceRecords = append(set.ResourceRecords, types.ResourceRecord{Value: aws.String(value)})
+	}
+	if s.Alias != nil {
+		set.AliasTarget = Alias{
+			Target:               s.Alias.Target,
+			HostedZoneID:         s.Alias.HostedZoneID,
+			EvaluateTargetHealth: s.Alias.EvaluateTargetHealth,
+		}.target()
+	}
+	if g := s.GeoLocation; g != nil {
+		set.GeoLocation = &types.GeoLocation{
+			ContinentCode:   optionalString(g.ContinentCode),
+			CountryCode:     optionalString(g.CountryCode),
+			SubdivisionCode: optionalString(g.SubdivisionCode),
+		}
+	}
+	if g := s.GeoProximity; g != nil {
+		set.GeoProximityLocation = &types.GeoProximityLocation{
+			AWSRegion:      optionalString(g.AWSRegion),
+			LocalZoneGroup: optionalString(g.LocalZoneGroup),
+			Bias:           g.Bias,
+		}
+		if g.Latitude != "" || g.Longitude != "" {
+			set.GeoProximityLocation.Coordinates = &types.Coordinates{
+				Latitude:  aws.String(g.Latitude),
+				Longitude: aws.String(g.Longitude),
+			}
+		}
+	}
+	if c := s.CIDRRouting; c != nil {
+		set.CidrRoutingConfig = &types.CidrRoutingConfig{
+			CollectionId: aws.String(c.CollectionID),
+			LocationName: aws.String(c.LocationName),
+		}
+	}
+	return set
+}
+
+// optionalString returns nil for an empty string, so it is left out of
+// Route53 requests.
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return aws.String(s)
+}
+
+// String describes the snapshot briefly.
+func (s Snapshot) String() string {
+	return fmt.Sprintf("snapshot of %s (%d record sets) taken %s",
+		s.Zone, len(s.RecordSets), s.Time.Format(time.RFC3339))
+}
@@ -0,0 +1,224 @@
+package route53 //nolint:testpackage // Testing internal functions
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/route53/types"
+)
+
+func TestSnapshotRecordSetRoundTrip(t *testing.T) {
+	sets := []types.ResourceRecordSet{
+		{
+			Name:            aws.String("\\052.example.com."),
+			Type:            types.RRTypeTxt,
+			TTL:             aws.Int64(300),
+			ResourceRecords: []types.ResourceRecord{{Value: aws.String(`"hello world"`)}},
+		},
+		{
+			Name:          aws.String("www.example.com."),
+			Type:          types.RRTypeA,
+			TTL:           aws.Int64(60),
+			SetIdentifier: aws.String("eu"),
+			Weight:        aws.Int64(10),
+			HealthCheckId: aws.String("hc-1"),
+			GeoLocation:   &types.GeoLocation{ContinentCode: aws.String("EU")},
+			ResourceRecords: []types.ResourceRecord{
+				{Value: aws.String("192.0.2.1")},
+				{Value: aws.String("192.0.2.2")},
+			},
+		},
+		{
+			Name:          aws.String("api.example.com."),
+			Type:          types.RRTypeAaaa,
+			SetIdentifier: aws.String("near"),
+			GeoProximityLocation: &types.GeoProximityLocation{
+				Bias:        aws.Int32(-10),
+				Coordinates: &types.Coordinates{Latitude: aws.String("49.22"), Longitude: aws.String("-74.01")},
+			},
+			CidrRoutingConfig: &types.CidrRoutingConfig{CollectionId: aws.String("c-1"), LocationName: aws.String("office")},
+			AliasTarget: &types.AliasTarget{
+				DNSName:              aws.String("dualstack.lb.example.net."),
+				HostedZoneId:         aws.String("Z35SXDOTRQ7X7K"),
+				EvaluateTargetHealth: true,
+			},
+		},
+		{
+			Name:             aws.String("example.com."),
+			Type:             types.RRTypeMx,
+			TTL:              aws.Int64(3600),
+			Failover:         types.ResourceRecordSetFailoverPrimary,
+			Region:           types.ResourceRecordSetRegionEuWest1,
+			MultiValueAnswer: aws.Bool(true),
+			SetIdentifier:    aws.String("primary"),
+			ResourceRecords:  []types.ResourceRecord{{Value: aws.String("10 mx.example.com.")}},
+		},
+	}
+
+	for _, set := range sets {
+		snap := snapshotRecordSet(set, "example.com.")
+
+		// survive encoding
+		data, err := json.Marshal(snap)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		var decoded SnapshotRecordSet
+		if err = json.Unmarshal(data, &decoded); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		got := decoded.resourceRecordSet("example.com.")
+		if !reflect.DeepEqual(*got, set) {
+			t.Errorf("record set changed in round trip:\nexpected %#v\ngot      %#v\nvia %s", set, *got, data)
+		}
+	}
+}
+
+func TestPlanRestore(t *testing.T) {
+	zone := "example.com."
+	apexNS := types.ResourceRecordSet{
+		Name:            aws.String(zone),
+		Type:            types.RRTypeNs,
+		TTL:             aws.Int64(172800),
+		ResourceRecords: []types.ResourceRecord{{Value: aws.String("ns-1.awsdns-01.org.")}},
+	}
+	weighted := func(id string, weight int64, ip string) types.ResourceRecordSet {
+		return types.ResourceRecordSet{
+			Name:            aws.String("www.example.com."),
+			Type:            types.RRTypeA,
+			TTL:             aws.Int64(60),
+			SetIdentifier:   aws.String(id),
+			Weight:          aws.Int64(weight),
+			ResourceRecords: []types.ResourceRecord{{Value: aws.String(ip)}},
+		}
+	}
+	policy := types.ResourceRecordSet{
+		Name:                    aws.String("tp.example.com."),
+		Type:                    types.RRTypeA,
+		TrafficPolicyInstanceId: aws.String("tpi-1"),
+		AliasTarget:             &types.AliasTarget{DNSName: aws.String("x.example.com."), HostedZoneId: aws.String("Z1")},
+	}
+	extra := types.ResourceRecordSet{
+		Name:            aws.String("extra.example.com."),
+		Type:            types.RRTypeTxt,
+		TTL:             aws.Int64(60),
+		ResourceRecords: []types.ResourceRecord{{Value: aws.String(`"x"`)}},
+	}
+
+	snapshot := Snapshot{Zone: zone, HostedZoneID: "Z1"}
+	for _, set := range []types.ResourceRecordSet{
+		apexNS, weighted("a", 10, "192.0.2.1"), weighted("b", 20, "192.0.2.2"),
+	} {
+		snapshot.RecordSets = append(snapshot.RecordSets, snapshotRecordSet(set, zone))
+	}
+
+	// the zone has drifted: "b" has another weight, "a" is gone, a record set
+	// was added, and a traffic policy record set the snapshot knows nothing of
+	current := []types.ResourceRecordSet{apexNS, weighted("b", 50, "192.0.2.2"), extra, policy}
+
+	changes, sets, err := planRestore(zone, current, snapshot, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(changes) != 3 || len(sets) != 3 {
+		t.Fatalf("expected 3 changes, got %d", len(changes))
+	}
+
+	if changes[0].Action != types.ChangeActionDelete || aws.ToString(changes[0].ResourceRecordSet.Name) != "extra.example.com." {
+		t.Errorf("expected the extra record set deleted first, got %+v", changes[0])
+	}
+	if len(sets[0].Before) != 1 || len(sets[0].After) != 0 {
+		t.Errorf("unexpected deletion journal: %+v", sets[0])
+	}
+
+	for i, id := range []string{"a", "b"} {
+		change := changes[i+1]
+		if change.Action != types.ChangeActionUpsert || aws.ToString(change.ResourceRecordSet.SetIdentifier) != id {
+			t.Errorf("expected set %q UPSERTed, got %+v", id, change)
+		}
+	}
+	if aws.ToInt64(changes[2].ResourceRecordSet.Weight) != 20 {
+		t.Errorf("expected the weight restored, got %d", aws.ToInt64(changes[2].ResourceRecordSet.Weight))
+	}
+	if len(sets[1].Before) != 0 || len(sets[2].Before) != 1 || len(sets[2].After) != 1 {
+		t.Errorf("unexpected UPSERT journal: %+v", sets[1:])
+	}
+
+	// nothing to do once restored
+	restored := []types.ResourceRecordSet{apexNS, weighted("b", 20, "192.0.2.2"), weighted("a", 10, "192.0.2.1"), policy}
+	if changes, _, err = planRestore(zone, restored, snapshot, true); err != nil || len(changes) != 0 {
+		t.Errorf("expected no changes, got %+v (%v)", changes, err)
+	}
+
+	// the apex NS of another hosted zone is not copied
+	otherNS := apexNS
+	otherNS.ResourceRecords = []types.ResourceRecord{{Value: aws.String("ns-2.awsdns-02.net.")}}
+	other := []types.ResourceRecordSet{otherNS, weighted("b", 20, "192.0.2.2"), weighted("a", 10, "192.0.2.1")}
+	if changes, _, err = planRestore(zone, other, snapshot, false); err != nil || len(changes) != 0 {
+		t.Errorf("expected no changes, got %+v (%v)", changes, err)
+	}
+}
+
+func TestRetargetAliases(t *testing.T) {
+	snapshot := Snapshot{Zone: "example.com.", HostedZoneID: "Z1", RecordSets: []SnapshotRecordSet{
+		{Name: "www", Type: "A", Alias: &SerializedAlias{Target: "origin.example.com.", HostedZoneID: "Z1"}},
+		{Name: "cdn", Type: "A", Alias: &SerializedAlias{Target: "d1.cloudfront.net.", HostedZoneID: "Z2FDTNDATAQYW2"}},
+	}}
+
+	retargeted := retargetAliases(snapshot, "Z2")
+	if id := retargeted.RecordSets[0].Alias.HostedZoneID; id != "Z2" {
+		t.Errorf("expected the same-zone alias retargeted, got %q", id)
+	}
+	if id := retargeted.RecordSets[1].Alias.HostedZoneID; id != "Z2FDTNDATAQYW2" {
+		t.Errorf("expected the CloudFront alias kept, got %q", id)
+	}
+	if id := snapshot.RecordSets[0].Alias.HostedZoneID; id != "Z1" {
+		t.Errorf("expected the snapshot left unchanged, got %q", id)
+	}
+}
+
+func TestRestoreLocksRecordSets(t *testing.T) {
+	cases := []struct {
+		name     string
+		snapshot string
+		locked   string
+	}{
+		{name: "plain", snapshot: "www", locked: "www"},
+		{name: "wildcard", snapshot: `\052`, locked: "*"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			provider := &Provider{HostedZoneID: "Z1"}
+			canned := newCannedProvider(t, provider,
+				cannedResponse{status: http.StatusOK, body: listRecordSetsResponse()},
+			)
+			snapshot := Snapshot{Zone: "example.com.", HostedZoneID: "Z1", RecordSets: []SnapshotRecordSet{
+				{Name: c.snapshot, Type: "A", TTL: aws.Int64(300), Values: []string{"192.0.2.1"}},
+			}}
+
+			unlock, err := provider.lockSet(context.Background(),
+				setLockKey{zoneID: hostedZonePrefix + "Z1", name: c.locked, recordType: "A"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer unlock()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			if _, err = provider.Restore(ctx, "example.com.", snapshot); !errors.Is(err, context.DeadlineExceeded) {
+				t.Errorf("expected Restore to wait for the record set lock, got %v", err)
+			}
+			if len(canned.requests) != 1 {
+				t.Errorf("expected no change submitted, got %d requests", len(canned.requests))
+			}
+		})
+	}
+}
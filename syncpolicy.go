@@ -0,0 +1,78 @@
+package route53
+
+import (
+	"context"
+)
+
+// Operations a SyncPolicy can refer to.
+const (
+	// OperationAppend is an AppendRecords call.
+	OperationAppend = "append"
+	// OperationSet is a SetRecords call.
+	OperationSet = "set"
+	// OperationDelete is a DeleteRecords call.
+	OperationDelete = "delete"
+	// OperationRevert is a Revert call.
+	OperationRevert = "revert"
+	// OperationRestore is a Restore call.
+	OperationRestore = "restore"
+	// OperationCopy is a CopyZone call.
+	OperationCopy = "copy"
+)
+
+// SyncPolicy refines WaitForRoute53Sync per operation and per record type.
+// Entries present in the maps take precedence over WaitForRoute53Sync, in
+// both directions: they can skip the wait when it is enabled globally, or
+// enable it when it is not.
+//
+// For example, with WaitForRoute53Sync disabled, this waits only for TXT
+// changes such as ACME challenges:
+//
+//	SyncPolicy{Types: map[string]bool{"TXT": true}}
+//
+// The decision for a change is taken from the first of these that applies:
+// WithSyncWait on the context, Types, Operations, SkipRoute53SyncOnDelete and
+// finally WaitForRoute53Sync.
+type SyncPolicy struct {
+	// Operations maps OperationAppend, OperationSet, OperationDelete,
+	// OperationRevert, OperationRestore or OperationCopy to whether changes
+	// made by that operation are waited for.
+	Operations map[string]bool `json:"operations,omitempty"`
+
+	// Types maps a record type, such as "TXT", to whether changes to record
+	// sets of that type are waited for.
+	Types map[string]bool `json:"types,omitempty"`
+}
+
+// WithSyncWait returns a context that overrides whether record methods called
+// with it wait for Route53 synchronization, regardless of WaitForRoute53Sync
+// and Route53SyncPolicy. It avoids configuring a second Provider for the odd
+// call that needs different behavior.
+func WithSyncWait(ctx context.Context, wait bool) context.Context {
+	return context.WithValue(ctx, contextKeySyncWait, wait)
+}
+
+// withOperation marks ctx as belonging to the given record operation so the
+// sync policy can be applied to the changes it submits.
+func withOperation(ctx context.Context, op string) context.Context {
+	return context.WithValue(ctx, contextKeyOperation, op)
+}
+
+// shouldWaitForSync decides whether a change to a record set of the given
+// type, submitted with ctx, is waited for.
+func (p *Provider) shouldWaitForSync(ctx context.Context, recordType string) bool {
+	if wait, ok := ctx.Value(contextKeySyncWait).(bool); ok {
+		return wait
+	}
+	if wait, ok := p.Route53SyncPolicy.Types[recordType]; ok {
+		return wait
+	}
+	op, _ := ctx.Value(contextKeyOperation).(string)
+	if wait, ok := p.Route53SyncPolicy.Operations[op]; ok {
+		return wait
+	}
+	if op == OperationDelete && p.SkipRoute53SyncOnDelete {
+		return false
+	}
+	return p.WaitForRoute53Sync
+}
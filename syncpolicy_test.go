@@ -0,0 +1,72 @@
+package route53 //nolint:testpackage // Testing internal functions
+
+import (
+	"context"
+	"testing"
+)
+
+func TestShouldWaitForSync(t *testing.T) {
+	cases := []struct {
+		name       string
+		wait       bool
+		skipDelete bool
+		policy     SyncPolicy
+		override   *bool
+		operation  string
+		recordType string
+		expected   bool
+	}{
+		{name: "disabled", operation: OperationAppend, recordType: "A", expected: false},
+		{name: "enabled", wait: true, operation: OperationAppend, recordType: "A", expected: true},
+		{
+			name: "skip on delete", wait: true, skipDelete: true,
+			operation: OperationDelete, recordType: "TXT", expected: false,
+		},
+		{
+			name: "skip on delete does not affect set", wait: true, skipDelete: true,
+			operation: OperationSet, recordType: "TXT", expected: true,
+		},
+		{
+			name: "operation policy skips", wait: true,
+			policy:    SyncPolicy{Operations: map[string]bool{OperationSet: false}},
+			operation: OperationSet, recordType: "A", expected: false,
+		},
+		{
+			name:      "type policy enables",
+			policy:    SyncPolicy{Types: map[string]bool{"TXT": true}},
+			operation: OperationAppend, recordType: "TXT", expected: true,
+		},
+		{
+			name: "type policy wins over skip on delete", wait: true, skipDelete: true,
+			policy:    SyncPolicy{Types: map[string]bool{"TXT": true}},
+			operation: OperationDelete, recordType: "TXT", expected: true,
+		},
+		{
+			name:      "type policy wins over operation policy",
+			policy:    SyncPolicy{Types: map[string]bool{"A": false}, Operations: map[string]bool{OperationAppend: true}},
+			operation: OperationAppend, recordType: "A", expected: false,
+		},
+		{
+			name: "context override", wait: true,
+			policy:   SyncPolicy{Types: map[string]bool{"TXT": true}},
+			override: new(bool), operation: OperationAppend, recordType: "TXT", expected: false,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			provider := &Provider{
+				WaitForRoute53Sync:      c.wait,
+				SkipRoute53SyncOnDelete: c.skipDelete,
+				Route53SyncPolicy:       c.policy,
+			}
+			ctx := withOperation(context.Background(), c.operation)
+			if c.override != nil {
+				ctx = WithSyncWait(ctx, *c.override)
+			}
+			if actual := provider.shouldWaitForSync(ctx, c.recordType); actual != c.expected {
+				t.Errorf("expected %v, got %v", c.expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,320 @@
+package route53
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	r53 "github.com/aws/aws-sdk-go-v2/service/route53"
+	"github.com/aws/smithy-go"
+	"github.com/aws/smithy-go/middleware"
+	"github.com/libdns/libdns"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	metricnoop "go.opentelemetry.io/otel/metric/noop"
+	"go.opentelemetry.io/otel/trace"
+	tracenoop "go.opentelemetry.io/otel/trace/noop"
+)
+
+// instrumentationName names the OpenTelemetry tracer and meter of the
+// provider.
+const instrumentationName = "github.com/libdns/route53"
+
+// Attribute keys of the spans and metrics of the provider.
+const (
+	attributeZone         = attribute.Key("route53.zone")
+	attributeChildZone    = attribute.Key("route53.child_zone")
+	attributeHostedZoneID = attribute.Key("route53.hosted_zone_id")
+	attributeRecordName   = attribute.Key("route53.record.name")
+	attributeRecordType   = attribute.Key("route53.record.type")
+	attributeRecordCount  = attribute.Key("route53.record_count")
+	attributeChangeCount  = attribute.Key("route53.change_count")
+	attributeChangeID     = attribute.Key("route53.change_id")
+	attributeChangeStatus = attribute.Key("route53.change_status")
+	attributeAttempts     = attribute.Key("route53.attempts")
+	attributeRPCSystem    = attribute.Key("rpc.system")
+	attributeRPCService   = attribute.Key("rpc.service")
+	attributeRPCMethod    = attribute.Key("rpc.method")
+	attributeErrorType    = attribute.Key("error.type")
+)
+
+// telemetry holds the OpenTelemetry instruments of a provider. Without a
+// TracerProvider or MeterProvider, they do nothing.
+type telemetry struct {
+	tracer trace.Tracer
+
+	apiCalls    metric.Int64Counter
+	apiRetries  metric.Int64Counter
+	apiThrottle metric.Int64Counter
+	apiDuration metric.Float64Histogram
+	syncWait    metric.Float64Histogram
+}
+
+// initTelemetry sets up the instruments of the provider. Failing to create
+// an instrument is logged, and the instrument does nothing.
+func (p *Provider) initTelemetry(ctx context.Context) {
+	tracerProvider := p.TracerProvider
+	if tracerProvider == nil {
+		tracerProvider = tracenoop.NewTracerProvider()
+	}
+	meterProvider := p.MeterProvider
+	if meterProvider == nil {
+		meterProvider = metricnoop.NewMeterProvider()
+	}
+
+	t := &p.telemetry
+	t.tracer = tracerProvider.Tracer(instrumentationName)
+	meter := meterProvider.Meter(instrumentationName)
+
+	t.apiCalls = p.int64Counter(ctx, meter, "route53.api.calls",
+		"Route53 API calls, including their retries.", "{call}")
+	t.apiRetries = p.int64Counter(ctx, meter, "route53.api.retries",
+		"Retried Route53 API call attempts.", "{attempt}")
+	t.apiThrottle = p.int64Counter(ctx, meter, "route53.api.throttles",
+		"Route53 API call attempts rejected by throttling.", "{attempt}")
+	t.apiDuration = p.float64Histogram(ctx, meter, "route53.api.duration",
+		"Duration of Route53 API calls, including their retries.")
+	t.syncWait = p.float64Histogram(ctx, meter, "route53.sync.duration",
+		"Time spent waiting for changes to be INSYNC.")
+}
+
+// int64Counter creates a counter, or a counter doing nothing if that fails.
+func (p *Provider) int64Counter(
+	ctx context.Context,
+	meter metric.Meter,
+	name, description, unit string,
+) metric.Int64Counter {
+	counter, err := meter.Int64Counter(name, metric.WithDescription(description), metric.WithUnit(unit))
+	if err != nil {
+		p.Logger.WarnContext(ctx, "failed to create metric", "name", name, "error", err)
+		counter, _ = metricnoop.Meter{}.Int64Counter(name)
+	}
+	return counter
+}
+
+// float64Histogram creates a histogram of durations in seconds, or a
+// histogram doing nothing if that fails.
+func (p *Provider) float64Histogram(
+	ctx context.Context,
+	meter metric.Meter,
+	name, description string,
+) metric.Float64Histogram {
+	histogram, err := meter.Float64Histogram(name, metric.WithDescription(description), metric.WithUnit("s"))
+	if err != nil {
+		p.Logger.WarnContext(ctx, "failed to create metric", "name", name, "error", err)
+		histogram, _ = metricnoop.Meter{}.Float64Histogram(name)
+	}
+	return histogram
+}
+
+// startSpan initializes the provider and starts the span of the public
+// method name. The returned function ends the span, recording the error err
+// points to; public methods defer it with their named error result:
+//
+//	ctx, end := p.startSpan(ctx, "GetRecords", zoneAttributes(zone, nil))
+//	defer end(&err)
+func (p *Provider) startSpan(
+	ctx context.Context,
+	name string,
+	attrs []attribute.KeyValue,
+) (context.Context, func(err *error)) {
+	p.init(ctx)
+	ctx, span := p.telemetry.start(ctx, "route53."+name, attrs...)
+	return ctx, func(err *error) {
+		recordSpanError(span, *err)
+		span.End()
+	}
+}
+
+// zoneAttributes returns the span attributes of a call on a zone and
+// records.
+func zoneAttributes(zone string, records []libdns.Record) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{attributeZone.String(zone)}
+	if records != nil {
+		attrs = append(attrs, attributeRecordCount.Int(len(records)))
+	}
+	return attrs
+}
+
+// delegationAttributes returns the span attributes of a call on the
+// delegation of child by parent.
+func delegationAttributes(parent, child string) []attribute.KeyValue {
+	return []attribute.KeyValue{attributeZone.String(parent), attributeChildZone.String(child)}
+}
+
+// changeAttributes returns the span attributes of a call on a change.
+func changeAttributes(id string) []attribute.KeyValue {
+	return []attribute.KeyValue{attributeChangeID.String(id)}
+}
+
+// changeCountAttributes returns the span attributes of a call on changes.
+func changeCountAttributes(changes []Change) []attribute.KeyValue {
+	return []attribute.KeyValue{attributeChangeCount.Int(len(changes))}
+}
+
+// recordSpanError marks the span as failed with err, if any.
+func recordSpanError(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
+
+// addTelemetryMiddleware adds the middleware tracing and measuring Route53
+// API calls to the stack of an operation.
+func (p *Provider) addTelemetryMiddleware(stack *middleware.Stack) error {
+	return stack.Initialize.Add(
+		middleware.InitializeMiddlewareFunc("route53.Telemetry", p.telemetryMiddleware),
+		middleware.After,
+	)
+}
+
+// telemetryMiddleware wraps a Route53 API call, with all its attempts, in a
+// client span, and counts the attempts, retries and throttling errors.
+func (p *Provider) telemetryMiddleware(
+	ctx context.Context,
+	in middleware.InitializeInput,
+	next middleware.InitializeHandler,
+) (middleware.InitializeOutput, middleware.Metadata, error) {
+	operation := awsmiddleware.GetOperationName(ctx)
+	ctx, span := p.telemetry.tracer.Start(ctx, "Route53."+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attributeRPCSystem.String("aws-api"),
+			attributeRPCService.String("Route53"),
+			attributeRPCMethod.String(operation),
+		),
+		trace.WithAttributes(inputAttributes(in.Parameters)...),
+	)
+	defer span.End()
+
+	start := time.Now()
+	out, metadata, err := next.HandleInitialize(ctx, in)
+	duration := time.Since(start)
+
+	attempts, throttles := 1, 0
+	if results, ok := retry.GetAttemptResults(metadata); ok && len(results.Results) > 0 {
+		attempts = len(results.Results)
+		for _, result := range results.Results {
+			if isThrottle(result.Err) {
+				throttles++
+			}
+		}
+	}
+
+	span.SetAttributes(attributeAttempts.Int(attempts))
+	span.SetAttributes(outputAttributes(out.Result)...)
+	recordSpanError(span, err)
+
+	code := ""
+	opAttrs := []attribute.KeyValue{attributeRPCMethod.String(operation)}
+	callAttrs := opAttrs
+	if err != nil {
+		code = errorType(err)
+		callAttrs = append(callAttrs, attributeErrorType.String(code))
+	}
+	p.metrics().ObserveAPICall(operation, code, duration)
+	p.telemetry.apiCalls.Add(ctx, 1, metric.WithAttributes(callAttrs...))
+	p.telemetry.apiDuration.Record(ctx, duration.Seconds(), metric.WithAttributes(callAttrs...))
+	if attempts > 1 {
+		p.telemetry.apiRetries.Add(ctx, int64(attempts-1), metric.WithAttributes(opAttrs...))
+	}
+	if throttles > 0 {
+		p.telemetry.apiThrottle.Add(ctx, int64(throttles), metric.WithAttributes(opAttrs...))
+	}
+
+	return out, metadata, err
+}
+
+// start starts a span, which does nothing if the provider was not
+// initialized.
+func (t *telemetry) start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	tracer := t.tracer
+	if tracer == nil {
+		tracer = tracenoop.Tracer{}
+	}
+	return tracer.Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// recordSyncWait records the time spent waiting for a change to be INSYNC.
+func (t *telemetry) recordSyncWait(ctx context.Context, duration time.Duration, err error) {
+	if t.syncWait == nil {
+		return
+	}
+	var attrs []attribute.KeyValue
+	if err != nil {
+		attrs = append(attrs, attributeErrorType.String(errorType(err)))
+	}
+	t.syncWait.Record(ctx, duration.Seconds(), metric.WithAttributes(attrs...))
+}
+
+// inputAttributes returns the span attributes describing the input of a
+// Route53 API call.
+func inputAttributes(input any) []attribute.KeyValue {
+	switch in := input.(type) {
+	case *r53.ListResourceRecordSetsInput:
+		return []attribute.KeyValue{attributeHostedZoneID.String(aws.ToString(in.HostedZoneId))}
+	case *r53.ChangeResourceRecordSetsInput:
+		attrs := []attribute.KeyValue{attributeHostedZoneID.String(aws.ToString(in.HostedZoneId))}
+		if in.ChangeBatch == nil {
+			return attrs
+		}
+		changes := in.ChangeBatch.Changes
+		attrs = append(attrs, attributeChangeCount.Int(len(changes)))
+		if len(changes) == 1 && changes[0].ResourceRecordSet != nil {
+			attrs = append(attrs,
+				attributeRecordName.String(aws.ToString(changes[0].ResourceRecordSet.Name)),
+				attributeRecordType.String(string(changes[0].ResourceRecordSet.Type)))
+		}
+		return attrs
+	case *r53.GetChangeInput:
+		return []attribute.KeyValue{attributeChangeID.String(aws.ToString(in.Id))}
+	case *r53.ListHostedZonesByNameInput:
+		return []attribute.KeyValue{attributeZone.String(aws.ToString(in.DNSName))}
+	case *r53.GetHostedZoneInput:
+		return []attribute.KeyValue{attributeHostedZoneID.String(aws.ToString(in.Id))}
+	default:
+		return nil
+	}
+}
+
+// outputAttributes returns the span attributes describing the output of a
+// Route53 API call.
+func outputAttributes(output any) []attribute.KeyValue {
+	switch out := output.(type) {
+	case *r53.ChangeResourceRecordSetsOutput:
+		if out.ChangeInfo != nil {
+			return []attribute.KeyValue{attributeChangeID.String(aws.ToString(out.ChangeInfo.Id))}
+		}
+	case *r53.GetChangeOutput:
+		if out.ChangeInfo != nil {
+			return []attribute.KeyValue{attributeChangeStatus.String(string(out.ChangeInfo.Status))}
+		}
+	}
+	return nil
+}
+
+// errorType returns the error.type attribute value of err: the Route53 error
+// code if any.
+func errorType(err error) string {
+	var apiErr smithy.APIError
+	switch {
+	case errors.As(err, &apiErr):
+		return apiErr.ErrorCode()
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	default:
+		return "_OTHER"
+	}
+}
+
+// isThrottle reports whether err is a throttling error.
+func isThrottle(err error) bool {
+	return err != nil && retry.IsErrorThrottles(retry.DefaultThrottles).IsErrorThrottle(err).Bool()
+}
@@ -0,0 +1,304 @@
+package route53 //nolint:testpackage // Testing internal functions
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/ratelimit"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	r53 "github.com/aws/aws-sdk-go-v2/service/route53"
+	"github.com/aws/smithy-go/middleware"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	metricnoop "go.opentelemetry.io/otel/metric/noop"
+	"go.opentelemetry.io/otel/trace"
+	tracenoop "go.opentelemetry.io/otel/trace/noop"
+)
+
+// cannedResponses answers HTTP requests with canned Route53 responses, in
+// order.
+type cannedResponses struct {
+	mu        sync.Mutex
+	responses []cannedResponse
+	requests  []*http.Request
+	bodies    []string
+}
+
+type cannedResponse struct {
+	status int
+	body   string
+}
+
+func (c *cannedResponses) Do(req *http.Request) (*http.Response, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.requests = append(c.requests, req)
+	var body []byte
+	if req.Body != nil {
+		body, _ = io.ReadAll(req.Body)
+	}
+	c.bodies = append(c.bodies, string(body))
+	if len(c.responses) == 0 {
+		return nil, errors.New("no more canned responses")
+	}
+	res := c.responses[0]
+	c.responses = c.responses[1:]
+	return &http.Response{
+		StatusCode: res.status,
+		Header:     http.Header{"Content-Type": {"text/xml"}, "X-Amzn-Requestid": {"req-1"}},
+		Body:       io.NopCloser(strings.NewReader(res.body)),
+		Request:    req,
+	}, nil
+}
+
+const (
+	throttlingResponse = `<ErrorResponse><Error><Type>Sender</Type><Code>Throttling</Code>` +
+		`<Message>Rate exceeded</Message></Error><RequestId>req-1</RequestId></ErrorResponse>`
+	getChangeResponse = `<GetChangeResponse xmlns="https://route53.amazonaws.com/doc/2013-04-01/">` +
+		`<ChangeInfo><Id>/change/C1</Id><Status>INSYNC</Status>` +
+		`<SubmittedAt>2024-01-02T03:04:05Z</SubmittedAt></ChangeInfo></GetChangeResponse>`
+)
+
+// newCannedProvider returns a provider whose Route53 client gets the given
+// responses, retrying without delay.
+func newCannedProvider(t *testing.T, p *Provider, responses ...cannedResponse) *cannedResponses {
+	t.Helper()
+	t.Setenv("AWS_REGION", "us-east-1")
+	p.init(context.Background())
+
+	canned := &cannedResponses{responses: responses}
+	p.client = r53.New(r53.Options{
+		Region:      "us-east-1",
+		Credentials: aws.AnonymousCredentials{},
+		HTTPClient:  canned,
+		Retryer: retry.NewStandard(func(o *retry.StandardOptions) {
+			o.Backoff = retry.BackoffDelayerFunc(func(int, error) (time.Duration, error) { return 0, nil })
+			o.RateLimiter = ratelimit.None
+		}),
+		APIOptions: []func(*middleware.Stack) error{p.addTelemetryMiddleware, p.addLoggingMiddleware},
+	})
+	return canned
+}
+
+// spanRecorder is a TracerProvider recording the spans ended, in order.
+type spanRecorder struct {
+	tracenoop.TracerProvider
+
+	mu    sync.Mutex
+	ended []*recordedSpan
+	ids   uint64
+}
+
+func (r *spanRecorder) Tracer(string, ...trace.TracerOption) trace.Tracer {
+	return recordingTracer{recorder: r}
+}
+
+func (r *spanRecorder) Ended() []*recordedSpan {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.ended)
+}
+
+type recordingTracer struct {
+	tracenoop.Tracer
+
+	recorder *spanRecorder
+}
+
+func (t recordingTracer) Start(
+	ctx context.Context,
+	name string,
+	opts ...trace.SpanStartOption,
+) (context.Context, trace.Span) {
+	t.recorder.mu.Lock()
+	t.recorder.ids++
+	var spanID trace.SpanID
+	binary.BigEndian.PutUint64(spanID[:], t.recorder.ids)
+	t.recorder.mu.Unlock()
+
+	config := trace.NewSpanStartConfig(opts...)
+	span := &recordedSpan{
+		recorder:   t.recorder,
+		name:       name,
+		parent:     trace.SpanContextFromContext(ctx),
+		attributes: config.Attributes(),
+		spanContext: trace.NewSpanContext(trace.SpanContextConfig{
+			TraceID: trace.TraceID{1},
+			SpanID:  spanID,
+		}),
+	}
+	return trace.ContextWithSpan(ctx, span), span
+}
+
+type recordedSpan struct {
+	tracenoop.Span
+
+	recorder    *spanRecorder
+	name        string
+	parent      trace.SpanContext
+	spanContext trace.SpanContext
+	attributes  []attribute.KeyValue
+	status      codes.Code
+	errors      int
+}
+
+func (s *recordedSpan) SpanContext() trace.SpanContext { return s.spanContext }
+func (s *recordedSpan) IsRecording() bool              { return true }
+func (s *recordedSpan) SetStatus(code codes.Code, _ string) {
+	s.status = code
+}
+
+func (s *recordedSpan) SetAttributes(attrs ...attribute.KeyValue) {
+	s.attributes = append(s.attributes, attrs...)
+}
+
+func (s *recordedSpan) RecordError(error, ...trace.EventOption) {
+	s.errors++
+}
+
+func (s *recordedSpan) End(...trace.SpanEndOption) {
+	s.recorder.mu.Lock()
+	defer s.recorder.mu.Unlock()
+	s.recorder.ended = append(s.recorder.ended, s)
+}
+
+// counterRecorder is a MeterProvider summing the values added to its
+// counters by name.
+type counterRecorder struct {
+	metricnoop.MeterProvider
+
+	mu   sync.Mutex
+	sums map[string]int64
+}
+
+func (r *counterRecorder) Meter(string, ...metric.MeterOption) metric.Meter {
+	return recordingMeter{recorder: r}
+}
+
+func (r *counterRecorder) Sum(name string) int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.sums[name]
+}
+
+type recordingMeter struct {
+	metricnoop.Meter
+
+	recorder *counterRecorder
+}
+
+func (m recordingMeter) Int64Counter(name string, _ ...metric.Int64CounterOption) (metric.Int64Counter, error) {
+	return recordingCounter{recorder: m.recorder, name: name}, nil
+}
+
+type recordingCounter struct {
+	metricnoop.Int64Counter
+
+	recorder *counterRecorder
+	name     string
+}
+
+func (c recordingCounter) Add(_ context.Context, value int64, _ ...metric.AddOption) {
+	c.recorder.mu.Lock()
+	defer c.recorder.mu.Unlock()
+	if c.recorder.sums == nil {
+		c.recorder.sums = make(map[string]int64)
+	}
+	c.recorder.sums[c.name] += value
+}
+
+func TestTelemetry(t *testing.T) {
+	spans := &spanRecorder{}
+	counters := &counterRecorder{}
+	provider := &Provider{TracerProvider: spans, MeterProvider: counters}
+	newCannedProvider(t, provider,
+		cannedResponse{status: http.StatusBadRequest, body: throttlingResponse},
+		cannedResponse{status: http.StatusOK, body: getChangeResponse},
+	)
+
+	change, err := provider.ChangeStatus(context.Background(), "C1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if change.Status != ChangeStatusInSync {
+		t.Errorf("expected INSYNC, got %q", change.Status)
+	}
+
+	ended := spans.Ended()
+	if len(ended) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(ended))
+	}
+	call, method := ended[0], ended[1]
+	if call.name != "Route53.GetChange" || method.name != "route53.ChangeStatus" {
+		t.Fatalf("unexpected spans %q and %q", call.name, method.name)
+	}
+	if call.parent.SpanID() != method.spanContext.SpanID() {
+		t.Error("expected the API call span to be a child of the method span")
+	}
+	attrs := attribute.NewSet(call.attributes...)
+	for key, expected := range map[attribute.Key]attribute.Value{
+		attributeRPCMethod:    attribute.StringValue("GetChange"),
+		attributeChangeID:     attribute.StringValue("/change/C1"),
+		attributeChangeStatus: attribute.StringValue("INSYNC"),
+		attributeAttempts:     attribute.IntValue(2),
+	} {
+		if got, ok := attrs.Value(key); !ok || got != expected {
+			t.Errorf("expected %s=%v, got %v", key, expected.Emit(), got.Emit())
+		}
+	}
+
+	for name, expected := range map[string]int64{
+		"route53.api.calls":     1,
+		"route53.api.retries":   1,
+		"route53.api.throttles": 1,
+	} {
+		if got := counters.Sum(name); got != expected {
+			t.Errorf("expected %s = %d, got %d", name, expected, got)
+		}
+	}
+}
+
+func TestStartSpan(t *testing.T) {
+	spans := &spanRecorder{}
+	provider := &Provider{TracerProvider: spans}
+
+	failure := errors.New("boom")
+	_, end := provider.startSpan(context.Background(), "DeleteZone", zoneAttributes("example.com.", nil))
+	end(&failure)
+
+	ended := spans.Ended()
+	if len(ended) != 1 || ended[0].name != "route53.DeleteZone" || ended[0].status != codes.Error {
+		t.Fatalf("expected a failed route53.DeleteZone span, got %+v", ended)
+	}
+	if ended[0].errors != 1 {
+		t.Error("expected the error recorded")
+	}
+
+	// a provider without a TracerProvider does nothing
+	var err error
+	_, end = (&Provider{}).startSpan(context.Background(), "GetRecords", nil)
+	end(&err)
+}
+
+func TestErrorType(t *testing.T) {
+	cases := map[string]error{
+		"timeout":  context.DeadlineExceeded,
+		"canceled": context.Canceled,
+		"_OTHER":   errors.New("boom"),
+	}
+	for expected, err := range cases {
+		if got := errorType(err); got != expected {
+			t.Errorf("expected %q for %v, got %q", expected, err, got)
+		}
+	}
+}
@@ -0,0 +1,297 @@
+package route53
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	r53 "github.com/aws/aws-sdk-go-v2/service/route53"
+	"github.com/aws/aws-sdk-go-v2/service/route53/types"
+	"github.com/libdns/libdns"
+)
+
+// hostedZonePrefix is the prefix Route53 puts in front of hosted zone IDs.
+const hostedZonePrefix = "/hostedzone/"
+
+// VPC identifies an Amazon VPC a private hosted zone is associated with.
+type VPC struct {
+	// ID is the VPC ID, for example "vpc-0123456789abcdef0".
+	ID string `json:"id"`
+
+	// Region is the region the VPC is in, for example "eu-west-1".
+	Region string `json:"region"`
+}
+
+// CreateZoneOptions configures CreateZone.
+type CreateZoneOptions struct {
+	// Comment is stored with the hosted zone.
+	Comment string `json:"comment,omitempty"`
+
+	// VPCs makes the hosted zone private and associates it with these VPCs.
+	// If empty, a public hosted zone is created.
+	VPCs []VPC `json:"vpcs,omitempty"`
+
+	// DelegationSetID is the ID of a reusable delegation set. If set, the
+	// hosted zone uses its name servers. Only valid for public zones.
+	DelegationSetID string `json:"delegation_set_id,omitempty"`
+
+	// CallerReference makes the request idempotent: creating a zone twice
+	// with the same reference returns an error instead of a second zone. If
+	// empty, a unique reference is generated.
+	CallerReference string `json:"caller_reference,omitempty"`
+}
+
+// Zone describes a Route53 hosted zone.
+type Zone struct {
+	// ID is the hosted zone ID without the "/hostedzone/" prefix, in the
+	// form expected by Provider.HostedZoneID.
+	ID string `json:"id"`
+
+	// Name is the fully qualified zone name, with a trailing dot.
+	Name string `json:"name"`
+
+	// Private is true for private hosted zones.
+	Private bool `json:"private,omitempty"`
+
+	// NameServers are the Route53 name servers the zone must be delegated
+	// to. They are empty for private zones.
+	NameServers []string `json:"name_servers,omitempty"`
+}
+
+// CreateZone creates a hosted zone and returns it along with its name
+// servers. With WaitForRoute53Sync enabled it waits for the zone to be
+// INSYNC; the creation change is reported to the context's ChangeRecorder.
+func (p *Provider) CreateZone(ctx context.Context, name string, opts CreateZoneOptions) (_ Zone, err error) {
+	ctx, end := p.startSpan(ctx, "CreateZone", zoneAttributes(name, nil))
+	defer end(&err)
+
+	name = absoluteZoneName(name)
+
+	callerReference := opts.CallerReference
+	if callerReference == "" {
+		if callerReference, err = newCallerReference(); err != nil {
+			return Zone{}, err
+		}
+	}
+
+	input := &r53.CreateHostedZoneInput{
+		Name:            aws.String(name),
+		CallerReference: aws.String(callerReference),
+		HostedZoneConfig: &types.HostedZoneConfig{
+			PrivateZone: len(opts.VPCs) > 0,
+		},
+	}
+	if opts.Comment != "" {
+		input.HostedZoneConfig.Comment = aws.String(opts.Comment)
+	}
+	if opts.DelegationSetID != "" {
+		input.DelegationSetId = aws.String(opts.DelegationSetID)
+	}
+	if len(opts.VPCs) > 0 {
+		// CreateHostedZone takes a single VPC, the others are associated
+		// once the zone exists.
+		input.VPC = &types.VPC{
+			VPCId:     aws.String(opts.VPCs[0].ID),
+			VPCRegion: types.VPCRegion(opts.VPCs[0].Region),
+		}
+	}
+
+	out, err := p.client.CreateHostedZone(ctx, input)
+	if err != nil {
+		var hzae *types.HostedZoneAlreadyExists
+		var idne *types.InvalidDomainName
+		switch {
+		case errors.As(err, &hzae):
+			return Zone{}, fmt.Errorf("HostedZoneAlreadyExists: %w", err)
+		case errors.As(err, &idne):
+			return Zone{}, fmt.Errorf("InvalidDomainName: %w", err)
+		default:
+			return Zone{}, err
+		}
+	}
+
+	zone := zoneFromHostedZone(out.HostedZone)
+	if out.DelegationSet != nil {
+		zone.NameServers = out.DelegationSet.NameServers
+	}
+
+	p.Logger.DebugContext(ctx, "created hosted zone",
+		"zone", name, "hosted_zone_id", zone.ID, "private", zone.Private)
+
+	for _, vpc := range opts.VPCs[min(1, len(opts.VPCs)):] {
+		_, err = p.client.AssociateVPCWithHostedZone(ctx, &r53.AssociateVPCWithHostedZoneInput{
+			HostedZoneId: aws.String(zone.ID),
+			VPC:          &types.VPC{VPCId: aws.String(vpc.ID), VPCRegion: types.VPCRegion(vpc.Region)},
+		})
+		if err != nil {
+			return zone, fmt.Errorf("associating VPC %s with zone %s: %w", vpc.ID, name, err)
+		}
+	}
+
+	_, err = p.settleChange(ctx, changeFromInfo(out.ChangeInfo), p.shouldWaitForSync(ctx, ""), "")
+	return zone, err
+}
+
+// DeleteZone deletes the hosted zone. Route53 refuses to delete a zone that
+// still contains record sets other than the apex SOA and NS; with force set,
+// those record sets are deleted first.
+//
+// The zone is always looked up by name, ignoring HostedZoneID, so a provider
+// configured for another zone cannot delete it by mistake.
+func (p *Provider) DeleteZone(ctx context.Context, name string, force bool) (err error) {
+	ctx, end := p.startSpan(ctx, "DeleteZone", zoneAttributes(name, nil))
+	defer end(&err)
+
+	name = absoluteZoneName(name)
+	zoneID, err := p.lookupZoneID(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	if force {
+		if err = p.emptyZone(ctx, zoneID, name); err != nil {
+			return err
+		}
+	}
+
+	out, err := p.client.DeleteHostedZone(ctx, &r53.DeleteHostedZoneInput{Id: aws.String(zoneID)})
+	if err != nil {
+		var hzne *types.HostedZoneNotEmpty
+		var nshze *types.NoSuchHostedZone
+		switch {
+		case errors.As(err, &hzne):
+			return fmt.Errorf("HostedZoneNotEmpty: %w", err)
+		case errors.As(err, &nshze):
+			return fmt.Errorf("NoSuchHostedZone: %w", err)
+		default:
+			return err
+		}
+	}
+
+	p.recordCache.invalidate(zoneID)
+	p.Logger.DebugContext(ctx, "deleted hosted zone", "zone", name, "hosted_zone_id", zoneID)
+
+	_, err = p.settleChange(ctx, changeFromInfo(out.ChangeInfo), p.shouldWaitForSync(ctx, ""), "")
+	return err
+}
+
+// HostedZones returns all the hosted zones visible to the provider. Name
+// servers are not included; they require a GetHostedZone call per zone.
+func (p *Provider) HostedZones(ctx context.Context) (_ []Zone, err error) {
+	ctx, end := p.startSpan(ctx, "HostedZones", nil)
+	defer end(&err)
+
+	var zones []Zone
+	paginator := r53.NewListHostedZonesPaginator(p.client, &r53.ListHostedZonesInput{})
+	for paginator.HasMorePages() {
+		page, pageErr := paginator.NextPage(ctx)
+		if pageErr != nil {
+			return nil, pageErr
+		}
+		for i := range page.HostedZones {
+			zones = append(zones, zoneFromHostedZone(&page.HostedZones[i]))
+		}
+	}
+	return zones, nil
+}
+
+// ListZones lists the names of all the hosted zones visible to the provider.
+// Public and private zones with the same name are listed once per zone.
+func (p *Provider) ListZones(ctx context.Context) (_ []libdns.Zone, err error) {
+	ctx, end := p.startSpan(ctx, "ListZones", nil)
+	defer end(&err)
+
+	hostedZones, err := p.HostedZones(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	zones := make([]libdns.Zone, 0, len(hostedZones))
+	for _, zone := range hostedZones {
+		zones = append(zones, libdns.Zone{Name: zone.Name})
+	}
+	return zones, nil
+}
+
+// emptyZone deletes every record set of the zone except the apex SOA and NS
+// record sets, which Route53 manages itself.
+func (p *Provider) emptyZone(ctx context.Context, zoneID, zone string) error {
+	sets, err := p.listRecordSets(ctx, zoneID)
+	if err != nil {
+		return err
+	}
+
+	var changes []types.Change
+	var deleted []RecordSetChange
+	for _, set := range sets {
+		if isApexDefault(set, zone) {
+			continue
+		}
+		changes = append(changes, types.Change{Action: types.ChangeActionDelete, ResourceRecordSet: &set})
+
+		records, parseErr := parseRecordSet(set, zone)
+		if parseErr != nil {
+			return parseErr
+		}
+		deleted = append(deleted, RecordSetChange{
+			Name:   libdns.RelativeName(aws.ToString(set.Name), zone),
+			Type:   string(set.Type),
+			Before: records,
+		})
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+
+	p.Logger.DebugContext(ctx, "emptying hosted zone before deletion",
+		"zone", zone, "record_sets", len(changes))
+
+	// Deleting the zone right after does not need the deletions to be INSYNC.
+	_, err = p.submitChanges(WithSyncWait(ctx, false), zoneID, zone, changes, deleted)
+	return err
+}
+
+// isApexDefault reports whether set is one of the SOA and NS record sets
+// Route53 creates at the apex of every hosted zone.
+func isApexDefault(set types.ResourceRecordSet, zone string) bool {
+	return (set.Type == types.RRTypeSoa || set.Type == types.RRTypeNs) &&
+		strings.EqualFold(aws.ToString(set.Name), zone)
+}
+
+// zoneFromHostedZone converts a Route53 HostedZone into a Zone.
+func zoneFromHostedZone(hz *types.HostedZone) Zone {
+	if hz == nil {
+		return Zone{}
+	}
+	zone := Zone{
+		ID:   strings.TrimPrefix(aws.ToString(hz.Id), hostedZonePrefix),
+		Name: aws.ToString(hz.Name),
+	}
+	if hz.Config != nil {
+		zone.Private = hz.Config.PrivateZone
+	}
+	return zone
+}
+
+// absoluteZoneName makes sure a zone name has a trailing dot.
+func absoluteZoneName(name string) string {
+	if strings.HasSuffix(name, ".") {
+		return name
+	}
+	return name + "."
+}
+
+// newCallerReference generates a unique CreateHostedZone caller reference.
+func newCallerReference() (string, error) {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	return "libdns-route53-" + strconv.FormatInt(time.Now().Unix(), 10) + "-" + hex.EncodeToString(b[:]), nil
+}
@@ -0,0 +1,83 @@
+package route53 //nolint:testpackage // Testing internal functions
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/route53/types"
+)
+
+func TestIsApexDefault(t *testing.T) {
+	cases := []struct {
+		name     string
+		set      types.ResourceRecordSet
+		expected bool
+	}{
+		{name: "apex SOA", set: types.ResourceRecordSet{Name: aws.String("example.com."), Type: types.RRTypeSoa}, expected: true},
+		{name: "apex NS", set: types.ResourceRecordSet{Name: aws.String("Example.com."), Type: types.RRTypeNs}, expected: true},
+		{name: "apex A", set: types.ResourceRecordSet{Name: aws.String("example.com."), Type: types.RRTypeA}},
+		{name: "delegation NS", set: types.ResourceRecordSet{Name: aws.String("sub.example.com."), Type: types.RRTypeNs}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if actual := isApexDefault(c.set, "example.com."); actual != c.expected {
+				t.Errorf("expected %v, got %v", c.expected, actual)
+			}
+		})
+	}
+}
+
+func TestZoneFromHostedZone(t *testing.T) {
+	zone := zoneFromHostedZone(&types.HostedZone{
+		Id:     aws.String("/hostedzone/Z123"),
+		Name:   aws.String("example.com."),
+		Config: &types.HostedZoneConfig{PrivateZone: true},
+	})
+	if zone.ID != "Z123" || zone.Name != "example.com." || !zone.Private {
+		t.Errorf("unexpected zone: %+v", zone)
+	}
+
+	if absoluteZoneName("example.com") != "example.com." || absoluteZoneName("example.com.") != "example.com." {
+		t.Error("expected zone names to be made absolute")
+	}
+}
+
+func TestNewCallerReference(t *testing.T) {
+	a, err := newCallerReference()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := newCallerReference()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a == b || !strings.HasPrefix(a, "libdns-route53-") {
+		t.Errorf("expected unique prefixed references, got %q and %q", a, b)
+	}
+}
+
+func TestDeleteZoneLooksUpName(t *testing.T) {
+	provider := &Provider{HostedZoneID: "ZOTHER"}
+	canned := newCannedProvider(t, provider,
+		cannedResponse{status: http.StatusOK, body: `<ListHostedZonesByNameResponse ` +
+			`xmlns="https://route53.amazonaws.com/doc/2013-04-01/"><HostedZones><HostedZone>` +
+			`<Id>/hostedzone/Z1</Id><Name>example.com.</Name><CallerReference>ref</CallerReference>` +
+			`</HostedZone></HostedZones><IsTruncated>false</IsTruncated><MaxItems>1</MaxItems>` +
+			`</ListHostedZonesByNameResponse>`},
+		cannedResponse{status: http.StatusOK, body: `<DeleteHostedZoneResponse ` +
+			`xmlns="https://route53.amazonaws.com/doc/2013-04-01/"><ChangeInfo><Id>/change/C1</Id>` +
+			`<Status>PENDING</Status><SubmittedAt>2024-01-02T03:04:05Z</SubmittedAt></ChangeInfo>` +
+			`</DeleteHostedZoneResponse>`},
+	)
+
+	if err := provider.DeleteZone(context.Background(), "example.com", false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(canned.requests) != 2 || canned.requests[1].URL.Path != "/2013-04-01/hostedzone/Z1" {
+		t.Errorf("expected the zone found by name deleted, got %d requests", len(canned.requests))
+	}
+}
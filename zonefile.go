@@ -0,0 +1,152 @@
+package route53
+
+import (
+	"bufio"
+	"cmp"
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+// aliasComment starts the comment lines WriteZoneFile writes for alias
+// records, which have no RFC 1035 representation. The line continues with
+// the name, the aliased type, the target, the target's hosted zone ID and
+// whether the target's health is evaluated, separated by tabs:
+//
+//	;ALIAS	cdn	A	d111111abcdef8.cloudfront.net.	Z2FDTNDATAQYW2	false
+//
+// Other tools ignore these lines as comments.
+const aliasComment = ";ALIAS"
+
+// ExportZone writes all records of the zone to w as an RFC 1035 (BIND)
+// master file. See WriteZoneFile for the format.
+func (p *Provider) ExportZone(ctx context.Context, zone string, w io.Writer) (err error) {
+	ctx, end := p.startSpan(ctx, "ExportZone", zoneAttributes(zone, nil))
+	defer end(&err)
+
+	zone = absoluteZoneName(zone)
+	zoneID, err := p.getZoneID(ctx, zone)
+	if err != nil {
+		return err
+	}
+
+	records, err := p.getRecords(ctx, zoneID, zone)
+	if err != nil {
+		return err
+	}
+
+	p.Logger.DebugContext(ctx, "exporting zone", "zone", zone, "records", len(records))
+
+	return WriteZoneFile(w, zone, records)
+}
+
+// WriteZoneFile writes records, with names relative to origin, to w as an
+// RFC 1035 (BIND) master file. The output starts with $ORIGIN and a $TTL set
+// to the most common TTL, and every record line carries its own TTL. Records
+// are written in a stable order, the apex SOA and NS first and then by name
+// and type, so exports of the same zone can be compared with diff or kept in
+// git.
+//
+// TXT and SPF data is split into quoted strings of at most 255 bytes. Alias
+// records are written as ";ALIAS" comment lines, a format specific to this
+// package that other tools ignore.
+func WriteZoneFile(w io.Writer, origin string, records []libdns.Record) error {
+	origin = absoluteZoneName(origin)
+
+	sorted := slices.Clone(records)
+	slices.SortStableFunc(sorted, compareZoneFileRecords)
+
+	bw := bufio.NewWriter(w)
+	_, _ = fmt.Fprintf(bw, "$ORIGIN %s\n", origin)
+	_, _ = fmt.Fprintf(bw, "$TTL %d\n", int64(defaultZoneFileTTL(records).Seconds()))
+
+	for _, record := range sorted {
+		if alias, ok := asAlias(record); ok {
+			_, _ = fmt.Fprintf(bw, "%s\t%s\t%s\t%s\t%s\t%t\n", aliasComment,
+				zoneFileName(alias.Name), alias.Type, alias.Target, alias.HostedZoneID, alias.EvaluateTargetHealth)
+			continue
+		}
+
+		rr := record.RR()
+		_, _ = fmt.Fprintf(bw, "%s\t%d\tIN\t%s\t%s\n",
+			zoneFileName(rr.Name), int64(rr.TTL.Seconds()), rr.Type, zoneFileData(rr))
+	}
+
+	return bw.Flush()
+}
+
+// zoneFileData returns the data of rr in master file form.
+func zoneFileData(rr libdns.RR) string {
+	switch rr.Type {
+	case "TXT", "SPF":
+		return quoteZoneFile(rr.Data)
+	default:
+		return rr.Data
+	}
+}
+
+// defaultZoneFileTTL returns the most common TTL among records, preferring
+// the lowest on a tie, or the default TTL if there are none.
+func defaultZoneFileTTL(records []libdns.Record) time.Duration {
+	counts := make(map[time.Duration]int)
+	for _, record := range records {
+		if _, ok := asAlias(record); ok {
+			continue
+		}
+		counts[record.RR().TTL]++
+	}
+
+	best, bestCount := defaultTTL*time.Second, 0
+	for ttl, count := range counts {
+		if count > bestCount || (count == bestCount && ttl < best) {
+			best, bestCount = ttl, count
+		}
+	}
+	return best
+}
+
+// compareZoneFileRecords orders records by name in DNSSEC canonical order
+// (RFC 4034, section 6.1) and then by type, with SOA and NS first.
+func compareZoneFileRecords(a, b libdns.Record) int {
+	ra, rb := a.RR(), b.RR()
+	if c := compareNames(ra.Name, rb.Name); c != 0 {
+		return c
+	}
+	return cmp.Compare(typeRank(ra.Type), typeRank(rb.Type))
+}
+
+// compareNames compares two relative names label by label, starting with the
+// rightmost label and ignoring case. The apex sorts first.
+func compareNames(a, b string) int {
+	return slices.Compare(reversedLabels(a), reversedLabels(b))
+}
+
+// reversedLabels returns the lowercased labels of a relative name from right
+// to left. The apex has no labels.
+func reversedLabels(name string) []string {
+	name = strings.ToLower(unquote(strings.TrimSuffix(name, ".")))
+	if name == "@" || name == "" {
+		return nil
+	}
+	labels := strings.Split(name, ".")
+	slices.Reverse(labels)
+	return labels
+}
+
+// typeRank returns a sort key putting SOA before NS before the other types
+// in alphabetical order.
+func typeRank(recordType string) string {
+	switch recordType {
+	case "SOA":
+		return "0"
+	case "NS":
+		return "1"
+	default:
+		return "2" + recordType
+	}
+}
@@ -0,0 +1,95 @@
+package route53 //nolint:testpackage // Testing internal functions
+
+import (
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+func TestWriteZoneFile(t *testing.T) {
+	records := []libdns.Record{
+		libdns.TXT{Name: "www", TTL: 300 * time.Second, Text: `say "hi" \ é`},
+		libdns.Address{Name: "www", TTL: 300 * time.Second, IP: netip.MustParseAddr("192.0.2.1")},
+		Alias{Name: "cdn", Type: "A", Target: "d111111abcdef8.cloudfront.net.", HostedZoneID: "Z2FDTNDATAQYW2"},
+		libdns.CNAME{Name: `\052`, TTL: 60 * time.Second, Target: "example.com."},
+		libdns.NS{Name: "@", TTL: 172800 * time.Second, Target: "ns-1.awsdns-01.org."},
+		libdns.RR{
+			Name: "@",
+			TTL:  900 * time.Second,
+			Type: "SOA",
+			Data: "ns-1.awsdns-01.org. awsdns-hostmaster.amazon.com. 1 7200 900 1209600 86400",
+		},
+		libdns.Address{Name: "a.www", TTL: 300 * time.Second, IP: netip.MustParseAddr("192.0.2.2")},
+	}
+
+	var sb strings.Builder
+	if err := WriteZoneFile(&sb, "example.com", records); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := strings.Join([]string{
+		"$ORIGIN example.com.",
+		"$TTL 300",
+		"@\t900\tIN\tSOA\tns-1.awsdns-01.org. awsdns-hostmaster.amazon.com. 1 7200 900 1209600 86400",
+		"@\t172800\tIN\tNS\tns-1.awsdns-01.org.",
+		"*\t60\tIN\tCNAME\texample.com.",
+		";ALIAS\tcdn\tA\td111111abcdef8.cloudfront.net.\tZ2FDTNDATAQYW2\tfalse",
+		"www\t300\tIN\tA\t192.0.2.1",
+		`www	300	IN	TXT	"say \"hi\" \\ \195\169"`,
+		"a.www\t300\tIN\tA\t192.0.2.2",
+		"",
+	}, "\n")
+	if sb.String() != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, sb.String())
+	}
+}
+
+func TestQuoteZoneFile(t *testing.T) {
+	cases := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "empty string",
+			input:    "",
+			expected: `""`,
+		},
+		{
+			name:     "control characters",
+			input:    "a\tb\x00",
+			expected: `"a\009b\000"`,
+		},
+		{
+			name:     "long string",
+			input:    strings.Repeat("a", 256),
+			expected: `"` + strings.Repeat("a", 255) + `" "a"`,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if actual := quoteZoneFile(c.input); actual != c.expected {
+				t.Errorf("expected %s, got %s", c.expected, actual)
+			}
+		})
+	}
+}
+
+func TestZoneFileName(t *testing.T) {
+	cases := map[string]string{
+		"@":            "@",
+		"www":          "www",
+		`\052.sub`:     "*.sub",
+		`odd\040label`: `odd\ label`,
+	}
+
+	for input, expected := range cases {
+		if actual := zoneFileName(input); actual != expected {
+			t.Errorf("zoneFileName(%q): expected %s, got %s", input, expected, actual)
+		}
+	}
+}
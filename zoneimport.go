@@ -0,0 +1,670 @@
+package route53
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/aws/aws-sdk-go-v2/service/route53/types"
+	"github.com/libdns/libdns"
+)
+
+// maxIncludeDepth limits the nesting of $INCLUDE directives, so a file
+// including itself fails instead of recursing forever.
+const maxIncludeDepth = 8
+
+// ImportZoneOptions configures ImportZone.
+type ImportZoneOptions struct {
+	// SkipApexSOA leaves the zone's SOA record set alone, even if records
+	// contains one.
+	SkipApexSOA bool `json:"skip_apex_soa,omitempty"`
+
+	// SkipApexNS leaves the zone's apex NS record set alone, even if records
+	// contains one. Set it when importing a file from another DNS provider,
+	// whose name servers the hosted zone must not point at.
+	SkipApexNS bool `json:"skip_apex_ns,omitempty"`
+}
+
+// ImportZone UPSERTs the record sets made of records into the zone, typically
+// records read with ParseZoneFile. Each record set in records replaces the
+// record set with the same name and type in the zone; record sets of the zone
+// absent from records are left alone. The record sets are changed under
+// their per-tuple locks, in batches like those of Restore. It returns the
+// records that were imported.
+func (p *Provider) ImportZone(
+	ctx context.Context,
+	zone string,
+	records []libdns.Record,
+	opts ImportZoneOptions,
+) (_ []libdns.Record, err error) {
+	ctx, end := p.startSpan(ctx, "ImportZone", zoneAttributes(zone, records))
+	defer end(&err)
+
+	ctx = withOperation(ctx, OperationSet)
+
+	zone = absoluteZoneName(zone)
+	zoneID, err := p.getZoneID(ctx, zone)
+	if err != nil {
+		return nil, err
+	}
+
+	recordSets := p.groupRecordsByKey(records)
+	var keys []recordSetKey
+	for _, key := range sortedRecordSetKeys(recordSets) {
+		if opts.skips(key) {
+			p.Logger.DebugContext(ctx, "skipping apex record set on import",
+				"zone", zone, "type", key.recordType)
+			continue
+		}
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		return nil, nil
+	}
+
+	unlock, err := p.lockSets(ctx, zoneID, keys)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	// The previous values are only needed to report or revert the changes.
+	// They are keyed by name as Route53 lists it, since the names of the
+	// file may differ in letter case or escapes.
+	var current map[recordSetKey][]libdns.Record
+	if p.needsBefore(ctx) {
+		existing, getErr := p.getRecords(ctx, zoneID, zone)
+		if getErr != nil {
+			return nil, getErr
+		}
+		current = make(map[recordSetKey][]libdns.Record)
+		for key, values := range p.groupRecordsByKey(existing) {
+			current[listedKey(key)] = values
+		}
+	}
+
+	var changes []types.Change
+	var sets []RecordSetChange
+	var imported []libdns.Record
+	for _, key := range keys {
+		set, buildErr := buildRecordSet(zone, key.name, key.recordType, recordSets[key])
+		if buildErr != nil {
+			return nil, buildErr
+		}
+		changes = append(changes, types.Change{Action: types.ChangeActionUpsert, ResourceRecordSet: set})
+		sets = append(sets, RecordSetChange{
+			Name:   key.name,
+			Type:   key.recordType,
+			Before: current[listedKey(key)],
+			After:  recordSets[key],
+		})
+		imported = append(imported, recordSets[key]...)
+	}
+
+	p.Logger.DebugContext(ctx, "importing zone", "zone", zone, "record_sets", len(changes))
+
+	ctx, waitDeferred := p.deferSync(ctx)
+
+	_, err = p.submitChanges(ctx, zoneID, zone, changes, sets)
+	unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	if err = waitDeferred(); err != nil {
+		return nil, err
+	}
+
+	if p.VerifyDNSPropagation {
+		if err = p.waitForDNS(ctx, zone, imported, true); err != nil {
+			return nil, err
+		}
+	}
+
+	return imported, nil
+}
+
+// listedKey returns key with its name as Route53 lists it, so names differing
+// only in letter case or escapes give the same key.
+func listedKey(key recordSetKey) recordSetKey {
+	return recordSetKey{name: route53Name(key.name), recordType: strings.ToUpper(key.recordType)}
+}
+
+// skips reports whether the options exclude the record set from an import.
+func (o ImportZoneOptions) skips(key recordSetKey) bool {
+	if key.name != "@" && key.name != "" {
+		return false
+	}
+	return (key.recordType == "SOA" && o.SkipApexSOA) || (key.recordType == "NS" && o.SkipApexNS)
+}
+
+// ParseZoneFile reads an RFC 1035 (BIND) master file from r and returns its
+// records, with names relative to origin. It supports $ORIGIN, $TTL and
+// $INCLUDE directives, relative and "@" names in owners and data, blank
+// owners, parentheses spanning lines, TTLs with BIND units such as "1h" and
+// TXT data made of several strings, which are joined. Records without a TTL
+// get the $TTL value, or else the last explicit TTL.
+//
+// Files named by $INCLUDE are read from fsys, which may be nil if the file
+// has none. The ";ALIAS" comment lines written by WriteZoneFile are read
+// back as Alias records. Only the IN class is supported, and every record
+// must be within origin.
+func ParseZoneFile(r io.Reader, origin string, fsys fs.FS) ([]libdns.Record, error) {
+	zp := &zoneParser{zone: absoluteZoneName(origin), fsys: fsys}
+	if err := zp.parse(r, "", zp.zone, 0); err != nil {
+		return nil, err
+	}
+	return zp.records, nil
+}
+
+// zoneParser holds the state of ParseZoneFile across included files.
+type zoneParser struct {
+	zone    string
+	fsys    fs.FS
+	records []libdns.Record
+
+	defaultTTL    time.Duration
+	hasDefaultTTL bool
+	lastTTL       time.Duration
+	hasLastTTL    bool
+}
+
+// zoneFileState is the part of the parser state that $INCLUDE does not pass
+// back to the including file.
+type zoneFileState struct {
+	origin string
+	owner  string
+}
+
+// parse reads the master file named file, or the main input if empty.
+func (zp *zoneParser) parse(r io.Reader, file, origin string, depth int) error {
+	src, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	entries, err := tokenizeZoneFile(string(src))
+	if err != nil {
+		return zoneFileError(file, err)
+	}
+
+	state := &zoneFileState{origin: origin}
+	for _, entry := range entries {
+		if err = zp.parseEntry(state, entry, depth); err != nil {
+			return zoneFileError(file, fmt.Errorf("line %d: %w", entry.line, err))
+		}
+	}
+	return nil
+}
+
+// zoneFileError prefixes err with the name of the included file it occurred
+// in, if any.
+func zoneFileError(file string, err error) error {
+	if file == "" {
+		return err
+	}
+	return fmt.Errorf("%s: %w", file, err)
+}
+
+// parseEntry interprets a single directive or record.
+func (zp *zoneParser) parseEntry(state *zoneFileState, entry zoneEntry, depth int) error {
+	if entry.alias != nil {
+		return zp.parseAlias(state, entry.alias)
+	}
+
+	tokens := entry.tokens
+	if !entry.blank && !tokens[0].quoted && strings.HasPrefix(tokens[0].text, "$") {
+		return zp.parseDirective(state, tokens, depth)
+	}
+
+	// owner, then TTL and class in either order, then type
+	owner := state.owner
+	if !entry.blank {
+		owner = resolveZoneFileName(tokens[0].text, state.origin)
+		tokens = tokens[1:]
+	}
+	if owner == "" {
+		return errors.New("record without an owner name")
+	}
+	state.owner = owner
+
+	ttl, hasTTL := time.Duration(0), false
+	for len(tokens) > 0 && !tokens[0].quoted {
+		text := tokens[0].text
+		if t, ok := parseZoneFileTTL(text); ok && !hasTTL {
+			ttl, hasTTL = t, true
+		} else if isZoneFileClass(text) {
+			// IN is the only class Route53 serves
+			if !strings.EqualFold(text, "IN") {
+				return fmt.Errorf("unsupported class %s", text)
+			}
+		} else {
+			break
+		}
+		tokens = tokens[1:]
+	}
+	if len(tokens) == 0 {
+		return errors.New("record without a type")
+	}
+	recordType := strings.ToUpper(tokens[0].text)
+
+	if hasTTL {
+		zp.lastTTL, zp.hasLastTTL = ttl, true
+	} else {
+		ttl = zp.implicitTTL()
+	}
+
+	name, err := zp.relativeName(owner)
+	if err != nil {
+		return err
+	}
+
+	data, err := zoneFileRecordData(recordType, tokens[1:], state.origin)
+	if err != nil {
+		return err
+	}
+
+	rr := libdns.RR{Name: name, TTL: ttl, Type: recordType, Data: data}
+	record, err := rr.Parse()
+	if err != nil {
+		return fmt.Errorf("failed to parse %s record %s: %w", recordType, name, err)
+	}
+	zp.records = append(zp.records, record)
+	return nil
+}
+
+// parseDirective interprets a $ORIGIN, $TTL or $INCLUDE directive.
+func (zp *zoneParser) parseDirective(state *zoneFileState, tokens []zoneToken, depth int) error {
+	directive := strings.ToUpper(tokens[0].text)
+	args := tokens[1:]
+
+	switch directive {
+	case "$ORIGIN":
+		if len(args) != 1 {
+			return errors.New("$ORIGIN takes a single domain name")
+		}
+		state.origin = resolveZoneFileName(args[0].text, state.origin)
+		return nil
+
+	case "$TTL":
+		if len(args) != 1 {
+			return errors.New("$TTL takes a single TTL")
+		}
+		ttl, ok := parseZoneFileTTL(args[0].text)
+		if !ok {
+			return fmt.Errorf("invalid TTL %s", args[0].text)
+		}
+		zp.defaultTTL, zp.hasDefaultTTL = ttl, true
+		return nil
+
+	case "$INCLUDE":
+		if len(args) < 1 || len(args) > 2 {
+			return errors.New("$INCLUDE takes a file name and an optional origin")
+		}
+		if zp.fsys == nil {
+			return fmt.Errorf("cannot include %s: no file system to read it from", args[0].text)
+		}
+		if depth >= maxIncludeDepth {
+			return fmt.Errorf("cannot include %s: $INCLUDE nested too deeply", args[0].text)
+		}
+
+		origin := state.origin
+		if len(args) == 2 {
+			origin = resolveZoneFileName(args[1].text, state.origin)
+		}
+
+		file := strings.TrimPrefix(args[0].text, "/")
+		f, err := zp.fsys.Open(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		return zp.parse(f, file, origin, depth+1)
+
+	default:
+		return fmt.Errorf("unsupported directive %s", tokens[0].text)
+	}
+}
+
+// parseAlias interprets the fields of an ";ALIAS" comment line.
+func (zp *zoneParser) parseAlias(state *zoneFileState, fields []string) error {
+	if len(fields) != 5 {
+		return fmt.Errorf("%s line needs name, type, target, hosted zone ID and evaluate target health", aliasComment)
+	}
+
+	name, err := zp.relativeName(resolveZoneFileName(fields[0], state.origin))
+	if err != nil {
+		return err
+	}
+	evaluateTargetHealth, err := strconv.ParseBool(fields[4])
+	if err != nil {
+		return fmt.Errorf("invalid evaluate target health %s: %w", fields[4], err)
+	}
+
+	zp.records = append(zp.records, Alias{
+		Name:                 name,
+		Type:                 strings.ToUpper(fields[1]),
+		Target:               fields[2],
+		HostedZoneID:         fields[3],
+		EvaluateTargetHealth: evaluateTargetHealth,
+	})
+	return nil
+}
+
+// implicitTTL returns the TTL of a record without an explicit one.
+func (zp *zoneParser) implicitTTL() time.Duration {
+	switch {
+	case zp.hasDefaultTTL:
+		return zp.defaultTTL
+	case zp.hasLastTTL:
+		return zp.lastTTL
+	default:
+		return defaultTTL * time.Second
+	}
+}
+
+// relativeName converts an absolute owner name into a name relative to the
+// zone being parsed, decoding escapes the way Route53 names expect.
+func (zp *zoneParser) relativeName(name string) (string, error) {
+	if strings.EqualFold(name, zp.zone) {
+		return "@", nil
+	}
+	if !strings.HasSuffix(strings.ToLower(name), "."+strings.ToLower(zp.zone)) {
+		return "", fmt.Errorf("%s is outside zone %s", name, zp.zone)
+	}
+	return unescapeZoneFile(name[:len(name)-len(zp.zone)-1], "."), nil
+}
+
+// zoneFileRecordData builds the libdns data of a record of the given type
+// from its RDATA tokens. Domain names in the data are made absolute.
+func zoneFileRecordData(recordType string, tokens []zoneToken, origin string) (string, error) {
+	if len(tokens) == 0 {
+		return "", errors.New("record without data")
+	}
+
+	switch recordType {
+	case "TXT", "SPF":
+		// libdns holds the strings of a TXT record joined together
+		var sb strings.Builder
+		for _, token := range tokens {
+			sb.WriteString(unescapeZoneFile(token.text, ""))
+		}
+		return sb.String(), nil
+
+	case "SOA":
+		// Route53 expects the SOA timers in seconds
+		for i := 2; i < len(tokens); i++ {
+			if ttl, ok := parseZoneFileTTL(tokens[i].text); ok {
+				tokens[i].text = strconv.FormatInt(int64(ttl.Seconds()), 10)
+			}
+		}
+	}
+
+	fields := make([]string, len(tokens))
+	for i, token := range tokens {
+		switch {
+		case token.quoted:
+			fields[i] = `"` + escapeZoneFile(unescapeZoneFile(token.text, ""), `"\`) + `"`
+		case slices.Contains(domainNameFields(recordType), i):
+			fields[i] = resolveZoneFileName(token.text, origin)
+		default:
+			fields[i] = token.text
+		}
+	}
+	return strings.Join(fields, " "), nil
+}
+
+// domainNameFields returns the positions of the domain names in the data of
+// records of the given type.
+func domainNameFields(recordType string) []int {
+	switch recordType {
+	case "CNAME", "NS", "PTR", "DNAME":
+		return []int{0}
+	case "MX", "HTTPS", "SVCB":
+		return []int{1}
+	case "SRV":
+		return []int{3}
+	case "SOA":
+		return []int{0, 1}
+	default:
+		return nil
+	}
+}
+
+// resolveZoneFileName makes a master file name absolute against origin.
+func resolveZoneFileName(name, origin string) string {
+	switch {
+	case name == "@":
+		return origin
+	case strings.HasSuffix(name, ".") && !strings.HasSuffix(name, `\.`):
+		return name
+	default:
+		return name + "." + origin
+	}
+}
+
+// parseZoneFileTTL parses a TTL in seconds, or in BIND's notation with
+// units, such as "1h30m" or "2D".
+func parseZoneFileTTL(s string) (time.Duration, bool) {
+	if s == "" || !unicode.IsDigit(rune(s[0])) {
+		return 0, false
+	}
+	if seconds, err := strconv.ParseUint(s, 10, 31); err == nil {
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	var total time.Duration
+	for s != "" {
+		i := strings.IndexFunc(s, func(r rune) bool { return !unicode.IsDigit(r) })
+		if i <= 0 {
+			return 0, false
+		}
+		n, err := strconv.ParseUint(s[:i], 10, 31)
+		if err != nil {
+			return 0, false
+		}
+
+		var unit time.Duration
+		switch unicode.ToLower(rune(s[i])) {
+		case 'w':
+			unit = 7 * 24 * time.Hour
+		case 'd':
+			unit = 24 * time.Hour
+		case 'h':
+			unit = time.Hour
+		case 'm':
+			unit = time.Minute
+		case 's':
+			unit = time.Second
+		default:
+			return 0, false
+		}
+		total += time.Duration(n) * unit
+		s = s[i+1:]
+	}
+	return total, true
+}
+
+// isZoneFileClass reports whether s names a DNS class.
+func isZoneFileClass(s string) bool {
+	switch strings.ToUpper(s) {
+	case "IN", "CH", "CS", "HS", "NONE", "ANY":
+		return true
+	default:
+		return strings.HasPrefix(strings.ToUpper(s), "CLASS")
+	}
+}
+
+// unescapeZoneFile decodes the RFC 1035 \DDD and \X escapes in s, except
+// for escaped characters in keep, whose escapes are left as they are.
+func unescapeZoneFile(s, keep string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c != '\\' || i+1 == len(s) {
+			sb.WriteByte(c)
+			continue
+		}
+		if i+3 < len(s) && isDigits(s[i+1:i+4]) {
+			if n, err := strconv.ParseUint(s[i+1:i+4], 10, 8); err == nil {
+				sb.WriteByte(byte(n))
+				i += 3
+				continue
+			}
+		}
+		if strings.IndexByte(keep, s[i+1]) >= 0 {
+			sb.WriteByte(c)
+		}
+		sb.WriteByte(s[i+1])
+		i++
+	}
+	return sb.String()
+}
+
+// isDigits reports whether s consists of ASCII digits only.
+func isDigits(s string) bool {
+	for i := range len(s) {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// zoneEntry is a logical line of a master file: a directive, a record, or an
+// ";ALIAS" comment line.
+type zoneEntry struct {
+	line   int
+	blank  bool
+	tokens []zoneToken
+	alias  []string
+}
+
+// zoneToken is a word of a master file. For quoted strings, text is the
+// content between the quotes, still escaped.
+type zoneToken struct {
+	text   string
+	quoted bool
+}
+
+// tokenizeZoneFile splits a master file into logical lines, joining the lines
+// inside parentheses and dropping comments.
+func tokenizeZoneFile(src string) ([]zoneEntry, error) {
+	var entries []zoneEntry
+	current := zoneEntry{line: 1}
+	line, depth := 1, 0
+	lineStart := true
+
+	flush := func() {
+		if len(current.tokens) > 0 || current.alias != nil {
+			entries = append(entries, current)
+		}
+		current = zoneEntry{line: line}
+	}
+	addToken := func(token zoneToken) {
+		if len(current.tokens) == 0 {
+			current.line = line
+		}
+		current.tokens = append(current.tokens, token)
+	}
+
+	for i := 0; i < len(src); {
+		c := src[i]
+		atLineStart := lineStart
+		lineStart = false
+
+		switch c {
+		case '\n':
+			line++
+			i++
+			if depth == 0 {
+				flush()
+				lineStart = true
+			}
+
+		case ' ', '\t', '\r':
+			if atLineStart && len(current.tokens) == 0 {
+				current.blank = true
+			}
+			i++
+
+		case ';':
+			end := strings.IndexByte(src[i:], '\n')
+			if end < 0 {
+				end = len(src) - i
+			}
+			comment := src[i : i+end]
+			if depth == 0 && len(current.tokens) == 0 && isAliasComment(comment) {
+				current.line = line
+				current.alias = strings.Fields(comment[len(aliasComment):])
+			}
+			i += end
+
+		case '(':
+			depth++
+			i++
+
+		case ')':
+			if depth == 0 {
+				return nil, fmt.Errorf("line %d: unbalanced parenthesis", line)
+			}
+			depth--
+			i++
+
+		case '"':
+			start := line
+			j := i + 1
+			for ; j < len(src) && src[j] != '"'; j++ {
+				switch src[j] {
+				case '\\':
+					if j+1 < len(src) && src[j+1] == '\n' {
+						line++
+					}
+					j++
+				case '\n':
+					line++
+				}
+			}
+			if j >= len(src) {
+				return nil, fmt.Errorf("line %d: unterminated quoted string", start)
+			}
+			addToken(zoneToken{text: src[i+1 : j], quoted: true})
+			i = j + 1
+
+		default:
+			j := i
+			for ; j < len(src) && !strings.ContainsRune(" \t\r\n;()\"", rune(src[j])); j++ {
+				if src[j] == '\\' {
+					j++
+				}
+			}
+			j = min(j, len(src))
+			addToken(zoneToken{text: src[i:j]})
+			i = j
+		}
+	}
+
+	if depth > 0 {
+		return nil, fmt.Errorf("line %d: unbalanced parenthesis", line)
+	}
+	flush()
+	return entries, nil
+}
+
+// isAliasComment reports whether a comment is an ";ALIAS" line.
+func isAliasComment(comment string) bool {
+	rest, ok := strings.CutPrefix(comment, aliasComment)
+	return ok && (rest == "" || rest[0] == ' ' || rest[0] == '\t')
+}
@@ -0,0 +1,206 @@
+package route53 //nolint:testpackage // Testing internal functions
+
+import (
+	"context"
+	"net/http"
+	"net/netip"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+func TestParseZoneFile(t *testing.T) {
+	zoneFile := `$ORIGIN example.com.
+$TTL 1h
+@	IN	SOA	ns1 hostmaster (
+		2024010101 ; serial
+		2h 15m 2w 1d )
+	IN	NS	ns1
+	IN	NS	ns2.example.net.
+ns1	300	IN	A	192.0.2.1
+www	IN 600	CNAME	@
+	TXT	"v=spf1 " "-all"
+mail	MX	10 mx
+_sip._tcp	SRV	0 5 5060 sip
+@	CAA	0 issue "letsencrypt.org"
+\042	A	192.0.2.2
+;ALIAS	cdn	A	d111111abcdef8.cloudfront.net.	Z2FDTNDATAQYW2	true
+$ORIGIN sub.example.com.
+host	A	192.0.2.3
+$INCLUDE extra.zone
+`
+	fsys := fstest.MapFS{
+		"extra.zone": {Data: []byte("included A 192.0.2.4\n")},
+	}
+
+	records, err := ParseZoneFile(strings.NewReader(zoneFile), "example.com", fsys)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []libdns.RR{
+		{Name: "@", TTL: time.Hour, Type: "SOA", Data: "ns1.example.com. hostmaster.example.com. 2024010101 7200 900 1209600 86400"},
+		{Name: "@", TTL: time.Hour, Type: "NS", Data: "ns1.example.com."},
+		{Name: "@", TTL: time.Hour, Type: "NS", Data: "ns2.example.net."},
+		{Name: "ns1", TTL: 300 * time.Second, Type: "A", Data: "192.0.2.1"},
+		{Name: "www", TTL: 600 * time.Second, Type: "CNAME", Data: "example.com."},
+		{Name: "www", TTL: time.Hour, Type: "TXT", Data: "v=spf1 -all"},
+		{Name: "mail", TTL: time.Hour, Type: "MX", Data: "10 mx.example.com."},
+		{Name: "_sip._tcp", TTL: time.Hour, Type: "SRV", Data: "0 5 5060 sip.example.com."},
+		{Name: "@", TTL: time.Hour, Type: "CAA", Data: `0 issue "letsencrypt.org"`},
+		{Name: "*", TTL: time.Hour, Type: "A", Data: "192.0.2.2"},
+		{Name: "cdn", Type: "A", Data: "d111111abcdef8.cloudfront.net."},
+		{Name: "host.sub", TTL: time.Hour, Type: "A", Data: "192.0.2.3"},
+		{Name: "included.sub", TTL: time.Hour, Type: "A", Data: "192.0.2.4"},
+	}
+	if len(records) != len(expected) {
+		t.Fatalf("expected %d records, got %d", len(expected), len(records))
+	}
+	for i, record := range records {
+		if rr := record.RR(); rr != expected[i] {
+			t.Errorf("record %d: expected %+v, got %+v", i, expected[i], rr)
+		}
+	}
+
+	alias, ok := records[10].(Alias)
+	if !ok || alias.HostedZoneID != "Z2FDTNDATAQYW2" || !alias.EvaluateTargetHealth {
+		t.Errorf("expected an alias record, got %#v", records[10])
+	}
+}
+
+func TestParseZoneFileRoundTrip(t *testing.T) {
+	records := []libdns.Record{
+		libdns.TXT{Name: "@", TTL: 300 * time.Second, Text: `quotes " backslashes \ é ` + strings.Repeat("x", 300)},
+		libdns.MX{Name: "@", TTL: 300 * time.Second, Preference: 10, Target: "mx.example.com."},
+		libdns.Address{Name: "*.dev", TTL: 60 * time.Second, IP: netip.MustParseAddr("2001:db8::1")},
+		Alias{Name: "cdn", Type: "AAAA", Target: "d111111abcdef8.cloudfront.net.", HostedZoneID: "Z2FDTNDATAQYW2"},
+	}
+
+	var sb strings.Builder
+	if err := WriteZoneFile(&sb, "example.com.", records); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	parsed, err := ParseZoneFile(strings.NewReader(sb.String()), "example.com.", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v\n%s", err, sb.String())
+	}
+	if len(parsed) != len(records) {
+		t.Fatalf("expected %d records, got %d", len(records), len(parsed))
+	}
+
+	// WriteZoneFile sorts the records
+	want := make(map[libdns.RR]bool)
+	for _, record := range records {
+		want[record.RR()] = true
+	}
+	for _, record := range parsed {
+		if !want[record.RR()] {
+			t.Errorf("unexpected record after round trip: %+v", record.RR())
+		}
+	}
+}
+
+func TestParseZoneFileErrors(t *testing.T) {
+	cases := []struct {
+		name     string
+		input    string
+		contains string
+	}{
+		{name: "outside origin", input: "www.example.org. 300 IN A 192.0.2.1\n", contains: "outside zone"},
+		{name: "unsupported class", input: "www 300 CH A 192.0.2.1\n", contains: "unsupported class CH"},
+		{name: "unbalanced parenthesis", input: "www 300 IN TXT ( \"a\"\n", contains: "unbalanced parenthesis"},
+		{name: "unterminated string", input: "www 300 IN TXT \"a\n", contains: "unterminated quoted string"},
+		{name: "missing owner", input: "  300 IN A 192.0.2.1\n", contains: "line 1: record without an owner name"},
+		{name: "include without file system", input: "$INCLUDE other.zone\n", contains: "no file system"},
+		{name: "invalid data", input: "www 300 IN A not-an-ip\n", contains: "failed to parse A record www"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := ParseZoneFile(strings.NewReader(c.input), "example.com.", nil)
+			if err == nil || !strings.Contains(err.Error(), c.contains) {
+				t.Errorf("expected error containing %q, got %v", c.contains, err)
+			}
+		})
+	}
+}
+
+func TestParseZoneFileTTL(t *testing.T) {
+	cases := map[string]time.Duration{
+		"300":   300 * time.Second,
+		"1h30m": 90 * time.Minute,
+		"2D":    48 * time.Hour,
+		"1w":    7 * 24 * time.Hour,
+	}
+	for input, expected := range cases {
+		if actual, ok := parseZoneFileTTL(input); !ok || actual != expected {
+			t.Errorf("parseZoneFileTTL(%q): expected %v, got %v (%v)", input, expected, actual, ok)
+		}
+	}
+
+	for _, input := range []string{"", "IN", "1x", "h1"} {
+		if _, ok := parseZoneFileTTL(input); ok {
+			t.Errorf("parseZoneFileTTL(%q): expected no TTL", input)
+		}
+	}
+}
+
+func TestImportZoneOptionsSkips(t *testing.T) {
+	opts := ImportZoneOptions{SkipApexSOA: true, SkipApexNS: true}
+	if !opts.skips(recordSetKey{name: "@", recordType: "SOA"}) || !opts.skips(recordSetKey{name: "@", recordType: "NS"}) {
+		t.Error("expected apex SOA and NS to be skipped")
+	}
+	if opts.skips(recordSetKey{name: "sub", recordType: "NS"}) || opts.skips(recordSetKey{name: "@", recordType: "A"}) {
+		t.Error("expected delegations and other apex records to be imported")
+	}
+	if (ImportZoneOptions{}).skips(recordSetKey{name: "@", recordType: "SOA"}) {
+		t.Error("expected nothing to be skipped by default")
+	}
+}
+
+func TestZoneFileRecordDataQuoted(t *testing.T) {
+	data, err := zoneFileRecordData("CAA", []zoneToken{
+		{text: "0"}, {text: "issue"}, {text: `ca.caf\233.example`, quoted: true},
+	}, "example.com.")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := `0 issue "ca.caf\233.example"`; data != expected {
+		t.Errorf("expected %s, got %s", expected, data)
+	}
+}
+
+func TestImportZoneBeforeValues(t *testing.T) {
+	provider := &Provider{HostedZoneID: "Z1"}
+	newCannedProvider(t, provider,
+		cannedResponse{status: http.StatusOK, body: listRecordSetsResponse(
+			recordSetXML("www.example.com.", "A", "192.0.2.1"),
+			recordSetXML("\\052.example.com.", "A", "192.0.2.2"),
+		)},
+		cannedResponse{status: http.StatusOK, body: changeResourceRecordSetsResponse},
+	)
+
+	records, err := ParseZoneFile(strings.NewReader("WWW 300 A 192.0.2.3\n* 300 A 192.0.2.4\n"), "example.com.", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var recorder ChangeRecorder
+	ctx := WithChangeRecorder(context.Background(), &recorder)
+	if _, err = provider.ImportZone(ctx, "example.com.", records, ImportZoneOptions{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	changes := recorder.Changes()
+	if len(changes) != 1 || len(changes[0].RecordSets) != 2 {
+		t.Fatalf("expected one change of 2 record sets, got %+v", changes)
+	}
+	for _, set := range changes[0].RecordSets {
+		if len(set.Before) != 1 {
+			t.Errorf("expected the current values of %s as before, got %v", set.Name, set.Before)
+		}
+	}
+}